@@ -0,0 +1,53 @@
+// Handling of the "cache" subcommand, which manages the local cache of
+// wspr.live query results.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Return dir if it is set, otherwise the default cache directory.
+func resolveCacheDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	return wspr.DefaultCacheDir()
+}
+
+// Entry point for "wspranalysis cache ...". args excludes the "cache" word.
+func runCacheCommand(args []string) {
+	flags := flag.NewFlagSet("cache prune", flag.ExitOnError)
+	maxAge := flags.Duration("max-age", 30*24*time.Hour, "Remove cache entries not used for this long (0 removes everything)")
+	cacheDir := flags.String("cache-dir", "", "`Directory` holding the cache (default: user cache directory)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cache prune [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Remove old entries from the local cache of wspr.live query results.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "prune" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 0 || *maxAge < 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir, err := resolveCacheDir(*cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	removed, err := wspr.PruneCache(dir, *maxAge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %d cache entries from %s\n", removed, dir)
+}
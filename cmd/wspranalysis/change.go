@@ -0,0 +1,77 @@
+// Handling of the "change" subcommand, which compares the windows before and
+// after a change to the target station.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Entry point for "wspranalysis change ...". args excludes the "change" word.
+func runChangeCommand(args []string) {
+	flags := flag.NewFlagSet("change", flag.ExitOnError)
+	common := addCommonFlagsWithoutTimeRange(flags)
+	changeTimeStr := flags.String("at", "", "`Time` of the change in RFC3339 format (required)")
+	window := flags.Duration("window", 7*24*time.Hour, "Length of the windows to compare before and after the change")
+	iterations := flags.Int("bootstrap", wspr.DefaultBootstrapIterations, "Number of bootstrap `iterations` for the confidence interval of the shift (0 to disable)")
+	seed := flags.Uint64("seed", 1, "Seed for the bootstrap random number generator")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s change -at [time] [options] [target callsign] [band]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare the aggregate metric for [target callsign] on [band] in the windows before and\n")
+		fmt.Fprintf(os.Stderr, "after a change to the station, such as a new antenna. The shift is reported with a\n")
+		fmt.Fprintf(os.Stderr, "bootstrap %.0f%% confidence interval and a Mann-Whitney U test.\n\n", wspr.ConfidenceLevel*100)
+		fmt.Fprintf(os.Stderr, "[band] is one of:\n\t%v\n\n", wspr.BandNames())
+		fmt.Fprintf(os.Stderr, "Other options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 || *changeTimeStr == "" {
+		flags.Usage()
+		return
+	}
+	target := wspr.NormaliseCallsign(flags.Arg(0))
+	band, err := wspr.BandNameToCode(flags.Arg(1))
+	if err != nil {
+		flags.Usage()
+		return
+	}
+	params, err := common.analysisParams(target, band)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	changeTime, err := time.Parse(time.RFC3339, *changeTimeStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid change time (%v)\n", err)
+		return
+	}
+	if *window <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: window length must be positive\n")
+		return
+	}
+	if *iterations < 0 {
+		fmt.Fprintf(os.Stderr, "Error: number of bootstrap iterations must not be negative\n")
+		return
+	}
+	params.BootstrapIterations = *iterations
+	params.BootstrapSeed = *seed
+	source, err := common.reportSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	result, err := wspr.RunChangeAnalysis(source, params, changeTime, *window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := wspr.WriteChangeText(os.Stdout, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+// Handling of the "compare" subcommand, which compares two transmitters head
+// to head.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Entry point for "wspranalysis compare ...". args excludes the "compare" word.
+func runCompareCommand(args []string) {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	common := addCommonFlags(flags, 24*time.Hour)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare [options] [callsign A] [callsign B] [band]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare two transmitters using the time slots in which the same receiver heard both of\n")
+		fmt.Fprintf(os.Stderr, "them on [band]. The difference in normalised SNR (A - B) is reported per receiver and as\n")
+		fmt.Fprintf(os.Stderr, "a median across receivers with a %.0f%% confidence interval.\n\n", wspr.ConfidenceLevel*100)
+		fmt.Fprintf(os.Stderr, "[band] is one of:\n\t%v\n\n", wspr.BandNames())
+		fmt.Fprintf(os.Stderr, "Other options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return
+	}
+	callsignA := wspr.NormaliseCallsign(flags.Arg(0))
+	callsignB := wspr.NormaliseCallsign(flags.Arg(1))
+	if callsignA == callsignB {
+		fmt.Fprintf(os.Stderr, "Error: callsigns A and B must be different\n\n")
+		flags.Usage()
+		return
+	}
+	band, err := wspr.BandNameToCode(flags.Arg(2))
+	if err != nil {
+		flags.Usage()
+		return
+	}
+	params, err := common.analysisParams(callsignA, band)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	// If either callsign matches the other, A's reports could be taken for
+	// B's and A compared with itself.
+	other := wspr.CallsignMatcher{Callsign: callsignB, Compound: params.CompoundCallsigns}
+	if params.Target().Match(callsignB) || slices.ContainsFunc(append([]string{callsignA}, params.TargetAliases...), other.Match) {
+		fmt.Fprintf(os.Stderr, "Error: %s and %s match each other's callsigns\n", callsignA, callsignB)
+		return
+	}
+	source, err := common.reportSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	result, err := wspr.RunComparison(source, params, callsignB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := wspr.WriteComparisonText(os.Stdout, result, *common.verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
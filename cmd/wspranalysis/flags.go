@@ -0,0 +1,307 @@
+// Command-line options shared by the analysis subcommands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Values of the options which are common to all the analysis subcommands.
+// startTimeStr and duration are nil for subcommands which choose their own
+// time range.
+type commonFlags struct {
+	normTxPwr        *int
+	startTimeStr     *string
+	duration         *time.Duration
+	verbose          *bool
+	noCache          *bool
+	cacheDir         *string
+	input            *string
+	filter           filterFlags
+	exclusions       exclusionFlags
+	outlierMode      *string
+	outlierThreshold *float64
+	aggregation      *string
+	targetLoc        *string
+	aliases          *string
+	compound         *bool
+	duplicates       *string
+}
+
+// Values of the options listing receivers and transmitters to exclude.
+type exclusionFlags struct {
+	file    *string
+	allowRx *string
+	denyRx  *string
+	allowTx *string
+	denyTx  *string
+}
+
+// Values of the options controlling which transmitters are compared with the
+// target.
+type filterFlags struct {
+	distancePercent  *float64
+	distanceWindow   *float64
+	minUpperBound    *float64
+	minComparables   *int
+	maxRxAzimuthDiff *float64
+	maxSeparation    *float64
+	minPower         *int
+	maxPower         *int
+}
+
+// Register the common options on flags. defaultDuration is the default value
+// of -duration; the default start time is that long before now.
+func addCommonFlags(flags *flag.FlagSet, defaultDuration time.Duration) *commonFlags {
+	c := addCommonFlagsWithoutTimeRange(flags)
+	defaultStartTimeStr := time.Now().UTC().Add(-defaultDuration).Format(time.RFC3339)
+	c.startTimeStr = flags.String("start", defaultStartTimeStr, "`Start time` for the query in RFC3339 format")
+	c.duration = flags.Duration("duration", defaultDuration, "Duration to analyse over (e.g., 24h, 30m)")
+	return c
+}
+
+// Register the common options except -start and -duration on flags.
+func addCommonFlagsWithoutTimeRange(flags *flag.FlagSet) *commonFlags {
+	return &commonFlags{
+		normTxPwr: flags.Int("norm", 43, "Transmit power in dBm to normalise SNRs for."),
+		verbose:   flags.Bool("v", false, "Enable verbose output"),
+		noCache:   flags.Bool("no-cache", false, "Always query wspr.live rather than using the local cache"),
+		cacheDir:  flags.String("cache-dir", "", "`Directory` for cached wspr.live results (default: user cache directory)"),
+		input:     flags.String("input", "", "Comma-separated list of wsprnet.org CSV archive `files` (or glob patterns) to analyse instead of querying wspr.live"),
+		filter: filterFlags{
+			distancePercent:  flags.Float64("distance-pct", 25, "Compare transmitters within this `percentage` of the target's distance from the receiver"),
+			distanceWindow:   flags.Float64("distance-window", 0, "Compare transmitters within this many `km` of the target's distance, if wider than -distance-pct"),
+			minUpperBound:    flags.Float64("min-upper-km", 50, "Minimum upper bound in `km` of the distance window"),
+			minComparables:   flags.Int("min-comparables", 1, "Drop receiver/time slot groups with fewer than this `number` of comparable transmitters"),
+			maxRxAzimuthDiff: flags.Float64("max-rx-azimuth-diff", 0, "Only compare transmitters whose azimuth at the receiver is within this many `degrees` of the target's (0 for no limit)"),
+			maxSeparation:    flags.Float64("max-separation", 0, "Only compare stations located within this many `km` of the target station, using their locators (0 for no limit)"),
+			minPower:         flags.Int("min-power", 0, "Only compare transmitters reporting at least this power in `dBm`"),
+			maxPower:         flags.Int("max-power", 60, "Only compare transmitters reporting at most this power in `dBm`"),
+		},
+		outlierMode:      flags.String("outliers", "warn", fmt.Sprintf("What to do with receivers whose SNR reports look unreliable, one of %v", wspr.OutlierModeNames())),
+		outlierThreshold: flags.Float64("outlier-threshold", wspr.DefaultOutlierThreshold, "Robust z-score beyond which a receiver's SNR spread makes it an outlier"),
+		aliases:          flags.String("alias", "", "Comma-separated other `callsigns` used by the target station"),
+		compound:         flags.Bool("compound", false, "Also match the target's callsign with any prefix or suffix (e.g. PA/K1ABC, K1ABC/P)"),
+		duplicates:       flags.String("duplicates", "max-snr", fmt.Sprintf("`Policy` for merging duplicate spots of a transmission by one receiver, one of %v", wspr.DuplicatePolicyNames())),
+		targetLoc:        flags.String("target-loc", "", "Only use reports in which the target station was within this Maidenhead `locator` square"),
+		aggregation:      flags.String("aggregate", "pooled", fmt.Sprintf("`Strategy` for combining samples into the aggregate metric, one of %v", wspr.AggregationStrategyNames())),
+		exclusions: exclusionFlags{
+			file:    flags.String("exclude-file", "", "`File` of allow/deny rules, one \"allow|deny rx|tx pattern\" per line"),
+			allowRx: flags.String("allow-rx", "", "Comma-separated callsign `patterns` (globs, or /regexps/) of the only receivers to use"),
+			denyRx:  flags.String("deny-rx", "", "Comma-separated callsign `patterns` (globs, or /regexps/) of receivers to exclude"),
+			allowTx: flags.String("allow-tx", "", "Comma-separated callsign `patterns` (globs, or /regexps/) of the only transmitters to compare with"),
+			denyTx:  flags.String("deny-tx", "", "Comma-separated callsign `patterns` (globs, or /regexps/) of transmitters not to compare with"),
+		},
+	}
+}
+
+// Build the exclusion lists from the -exclude-file and the pattern options.
+// Returns nil if nothing is to be excluded.
+func (f exclusionFlags) exclusionLists() (*wspr.ExclusionLists, error) {
+	var lists wspr.ExclusionLists
+	if *f.file != "" {
+		var err error
+		if lists, err = wspr.LoadExclusionLists(*f.file); err != nil {
+			return nil, err
+		}
+	}
+	for _, option := range []struct {
+		patterns *string
+		list     *[]wspr.CallsignPattern
+	}{
+		{f.allowRx, &lists.Receivers.Allow},
+		{f.denyRx, &lists.Receivers.Deny},
+		{f.allowTx, &lists.Transmitters.Allow},
+		{f.denyTx, &lists.Transmitters.Deny},
+	} {
+		if *option.patterns == "" {
+			continue
+		}
+		for _, text := range strings.Split(*option.patterns, ",") {
+			pattern, err := wspr.ParseCallsignPattern(text)
+			if err != nil {
+				return nil, err
+			}
+			*option.list = append(*option.list, pattern)
+		}
+	}
+	if lists.Empty() {
+		return nil, nil
+	}
+	return &lists, nil
+}
+
+// Validate the filter options and build the corresponding filter chain.
+func (f filterFlags) filterChain() (*wspr.FilterChain, error) {
+	if *f.distancePercent < 0 || *f.distanceWindow < 0 || *f.minUpperBound < 0 {
+		return nil, fmt.Errorf("distance window options must not be negative")
+	}
+	if *f.minComparables < 1 {
+		return nil, fmt.Errorf("minimum number of comparable transmitters must be at least 1")
+	}
+	if *f.maxRxAzimuthDiff < 0 || *f.maxRxAzimuthDiff > 180 {
+		return nil, fmt.Errorf("maximum azimuth difference must be between 0 and 180 degrees")
+	}
+	if *f.maxSeparation < 0 {
+		return nil, fmt.Errorf("maximum separation must not be negative")
+	}
+	if *f.minPower < 0 || *f.maxPower > 60 || *f.minPower > *f.maxPower {
+		return nil, fmt.Errorf("power range must be within 0 to 60 dBm")
+	}
+	chain := &wspr.FilterChain{
+		Rules: []wspr.ComparabilityRule{wspr.DistanceRule{
+			Percent:          *f.distancePercent,
+			Window_km:        *f.distanceWindow,
+			MinUpperBound_km: *f.minUpperBound,
+		}},
+		MinComparables: *f.minComparables,
+	}
+	if *f.maxRxAzimuthDiff > 0 {
+		chain.Rules = append(chain.Rules, wspr.RxAzimuthRule{MaxDifference_deg: *f.maxRxAzimuthDiff})
+	}
+	if *f.maxSeparation > 0 {
+		chain.Rules = append(chain.Rules, wspr.ProximityRule{MaxSeparation_km: *f.maxSeparation})
+	}
+	if *f.minPower > 0 || *f.maxPower < 60 {
+		chain.Rules = append(chain.Rules, wspr.PowerRule{Min_dBm: int8(*f.minPower), Max_dBm: int8(*f.maxPower)})
+	}
+	return chain, nil
+}
+
+// Validate the common options and build the parameters for analysing target
+// on band. The time range is left empty if -start and -duration were not
+// registered.
+func (c *commonFlags) analysisParams(target string, band int) (wspr.AnalysisParams, error) {
+	if *c.normTxPwr < -128 || *c.normTxPwr > 127 {
+		return wspr.AnalysisParams{}, fmt.Errorf("normalised transmit power must be between -128 and 127 dBm")
+	}
+	filter, err := c.filter.filterChain()
+	if err != nil {
+		return wspr.AnalysisParams{}, err
+	}
+	exclusions, err := c.exclusions.exclusionLists()
+	if err != nil {
+		return wspr.AnalysisParams{}, err
+	}
+	outlierMode, err := wspr.ParseOutlierMode(*c.outlierMode)
+	if err != nil {
+		return wspr.AnalysisParams{}, err
+	}
+	if *c.outlierThreshold <= 0 {
+		return wspr.AnalysisParams{}, fmt.Errorf("outlier threshold must be positive")
+	}
+	aggregation, err := wspr.ParseAggregationStrategy(*c.aggregation)
+	if err != nil {
+		return wspr.AnalysisParams{}, err
+	}
+	duplicates, err := wspr.ParseDuplicatePolicy(*c.duplicates)
+	if err != nil {
+		return wspr.AnalysisParams{}, err
+	}
+	if *c.targetLoc != "" {
+		if _, _, err := wspr.LocatorToLatLon(*c.targetLoc); err != nil {
+			return wspr.AnalysisParams{}, err
+		}
+	}
+	var aliases []string
+	if *c.aliases != "" {
+		for _, alias := range strings.Split(*c.aliases, ",") {
+			if alias = wspr.NormaliseCallsign(alias); alias == "" {
+				return wspr.AnalysisParams{}, fmt.Errorf("empty callsign in -alias")
+			}
+			aliases = append(aliases, alias)
+		}
+	}
+	params := wspr.AnalysisParams{
+		TargetCallsign:    target,
+		TargetAliases:     aliases,
+		CompoundCallsigns: *c.compound,
+		Band:              band,
+		NormTxPwr_dBm:     int8(*c.normTxPwr),
+		Filter:            filter,
+		Exclusions:        exclusions,
+		OutlierMode:       outlierMode,
+		OutlierThreshold:  *c.outlierThreshold,
+		Aggregation:       aggregation,
+		TargetLocator:     *c.targetLoc,
+		Duplicates:        duplicates,
+	}
+	if c.startTimeStr != nil {
+		startTime, err := time.Parse(time.RFC3339, *c.startTimeStr)
+		if err != nil {
+			return wspr.AnalysisParams{}, fmt.Errorf("invalid start time (%w)", err)
+		}
+		params.StartTime = startTime
+		params.Duration = *c.duration
+	}
+	return params, nil
+}
+
+// Build the ReportSource selected by the common options: local CSV files if
+// -input is given, otherwise wspr.live (cached unless -no-cache is given).
+func (c *commonFlags) reportSource() (wspr.ReportSource, error) {
+	var source wspr.ReportSource = &wspr.WsprLiveSource{}
+	if *c.input != "" {
+		paths, err := expandInputPaths(*c.input)
+		if err != nil {
+			return nil, err
+		}
+		return &wspr.CSVSource{Paths: paths}, nil
+	}
+	if !*c.noCache {
+		dir, err := resolveCacheDir(*c.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		source = &wspr.CachingSource{Upstream: source, Dir: dir}
+	}
+	return source, nil
+}
+
+// Expand a comma-separated list of file names and glob patterns into a list of
+// file paths. Returns an error if any pattern matches nothing.
+func expandInputPaths(input string) ([]string, error) {
+	var paths []string
+	for _, pattern := range strings.Split(input, ",") {
+		matches, err := filepath.Glob(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %q (%w)", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files match %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// Parse a comma-separated list of strictly increasing numbers.
+func parseIncreasingFloats(list string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		if len(values) > 0 && value <= values[len(values)-1] {
+			return nil, fmt.Errorf("values must be in increasing order")
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Format a list of numbers in the form accepted by parseIncreasingFloats.
+func formatFloatList(values []float64) string {
+	fields := make([]string, len(values))
+	for i, value := range values {
+		fields[i] = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return strings.Join(fields, ",")
+}
@@ -1,30 +1,65 @@
 // Main entry point for the wspranalysis command-line tool.
 // The code here mostly just handles argument parsing. The main
-// analysis logic is in the internal/wspranalysis package.
+// analysis logic is in the pkg/wspr package.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"strconv"
 	"time"
 
-	"github.com/jesse-/wspranalysis/internal/wspranalysis"
+	"github.com/jesse-/wspranalysis/pkg/wspr"
 )
 
 func main() {
-	defaultStartTimeStr := time.Now().UTC().Add(-24 * time.Hour).Format(time.RFC3339)
-	normTxPwr := flag.Int("norm", 43, "Transmit power in dBm to normalise SNRs for.")
-	startTimeStr := flag.String("start", defaultStartTimeStr, "`Start time` for the query in RFC3339 format")
-	duration := flag.Duration("duration", 24*time.Hour, "Duration to analyse over (e.g., 24h, 30m)")
-	verbose := flag.Bool("v", false, "Enable verbose output")
+	// Subcommands are dispatched before the main flags are parsed since they
+	// have their own flag sets.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			runCacheCommand(os.Args[2:])
+			return
+		case "compare":
+			runCompareCommand(os.Args[2:])
+			return
+		case "trend":
+			runTrendCommand(os.Args[2:])
+			return
+		case "change":
+			runChangeCommand(os.Args[2:])
+			return
+		case "rx":
+			runRxCommand(os.Args[2:])
+			return
+		}
+	}
+
+	common := addCommonFlags(flag.CommandLine, 24*time.Hour)
+	formatName := flag.String("format", "text", fmt.Sprintf("Output `format`, one of %v", wspr.OutputFormatNames()))
+	byAzimuth := flag.Bool("by-azimuth", false, "Break the results down by azimuth of the receivers from the target")
+	sectorWidth := flag.Float64("sector-width", 22.5, "Width of the azimuth sectors in `degrees` for -by-azimuth")
+	byDistance := flag.Bool("by-distance", false, "Break the results down by distance of the receivers from the target")
+	distanceBands := flag.String("distance-bands", formatFloatList(wspr.DefaultDistanceBands_km), "Comma-separated lower bounds in `km` of the distance bands for -by-distance")
+	hourly := flag.Bool("hourly", false, "Break the results down by hour of day (UTC, or local solar time with -solar-lon)")
+	solarLongitude := flag.String("solar-lon", "", "Use local solar time at this `longitude` (degrees, positive east) for -hourly")
+	charts := flag.Bool("chart", false, "Draw bar charts of the breakdowns as well as tables (text format only)")
+	iterations := flag.Int("bootstrap", wspr.DefaultBootstrapIterations, "Number of bootstrap `iterations` for the confidence interval of the aggregate metric (0 to disable)")
+	seed := flag.Uint64("seed", 1, "Seed for the bootstrap random number generator")
+	splitLoc := flag.Bool("split-loc", false, "Analyse each locator the target reported from separately")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [target callsign] [band]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [target callsign] [band]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare [options] [callsign A] [callsign B] [band]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s trend [options] [target callsign] [band]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s change -at [time] [options] [target callsign] [band]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s rx [options] [receiver callsign] [band]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cache prune [options]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Query the wspr.live database for reception reports of [target callsign] on [band].\n")
 		fmt.Fprintf(os.Stderr, "Each reception report is ranked against other transmitters heard by the same receiver\n")
 		fmt.Fprintf(os.Stderr, "at the same time.\n\n")
-		fmt.Fprintf(os.Stderr, "[band] is one of:\n\t%v\n\n", wspranalysis.BandNames())
+		fmt.Fprintf(os.Stderr, "[band] is one of the following, a comma-separated list of them or \"all\" (several\n")
+		fmt.Fprintf(os.Stderr, "bands give a summary table of the aggregate metric per band):\n\t%v\n\n", wspr.BandNames())
 		fmt.Fprintf(os.Stderr, "Other options:\n")
 		flag.PrintDefaults()
 	}
@@ -33,25 +68,101 @@ func main() {
 		flag.Usage()
 		return
 	}
-	target := strings.ToUpper(flag.Args()[0])
-	bandName := strings.ToLower(flag.Args()[1])
-	band, err := wspranalysis.BandNameToCode(bandName)
+	target := wspr.NormaliseCallsign(flag.Args()[0])
+	bands, err := wspr.ParseBandList(flag.Args()[1])
 	if err != nil {
 		flag.Usage()
 		return
 	}
-	startTime, err := time.Parse(time.RFC3339, *startTimeStr)
+	params, err := common.analysisParams(target, bands[0])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing start time: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if *byAzimuth {
+		if *sectorWidth < wspr.MinSectorWidth_deg || *sectorWidth > 360 {
+			fmt.Fprintf(os.Stderr, "Error: sector width must be between %g and 360 degrees\n", wspr.MinSectorWidth_deg)
+			return
+		}
+		params.AzimuthSectorWidth_deg = *sectorWidth
+	}
+	if *byDistance {
+		bands, err := parseIncreasingFloats(*distanceBands)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid distance bands (%v)\n", err)
+			return
+		}
+		params.DistanceBands_km = bands
+	}
+	if *hourly {
+		params.HourlyProfile = true
+		if *solarLongitude != "" {
+			longitude, err := strconv.ParseFloat(*solarLongitude, 64)
+			if err != nil || longitude < -180 || longitude > 180 {
+				fmt.Fprintf(os.Stderr, "Error: solar time longitude must be between -180 and 180 degrees\n")
+				return
+			}
+			params.SolarTime = true
+			params.TargetLongitude_deg = longitude
+		}
+	}
+	if *iterations < 0 {
+		fmt.Fprintf(os.Stderr, "Error: number of bootstrap iterations must not be negative\n")
 		return
 	}
-	if *normTxPwr < -128 || *normTxPwr > 127 {
-		fmt.Fprintf(os.Stderr, "Error: Normalised transmit power must be between -128 and 127 dBm\n")
+	params.BootstrapIterations = *iterations
+	params.BootstrapSeed = *seed
+	format, err := wspr.ParseOutputFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	source, err := common.reportSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return
 	}
 
-	if err := wspranalysis.RunAnalysis(target, band, startTime, *duration, int8(*normTxPwr), *verbose); err != nil {
+	if *splitLoc {
+		if len(bands) > 1 || params.TargetLocator != "" {
+			fmt.Fprintf(os.Stderr, "Error: -split-loc cannot be used with several bands or -target-loc\n")
+			return
+		}
+		result, err := wspr.RunLocatorSplitAnalysis(source, params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := wspr.WriteLocatorSplit(os.Stdout, result, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(bands) > 1 {
+		result, err := wspr.RunMultiBandAnalysis(source, params, bands)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := wspr.WriteMultiBand(os.Stdout, result, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	result, err := wspr.RunAnalysis(source, params)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if err := wspr.WriteResult(os.Stdout, result, format, wspr.TextOptions{Verbose: *common.verbose, Charts: *charts}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// The CSV table has one row per sample, so there is nowhere in it for the
+	// number of duplicates.
+	if (format == wspr.FormatCSV || format == wspr.FormatTSV) && result.DuplicatesRemoved > 0 {
+		fmt.Fprintf(os.Stderr, "Removed %d duplicate spots (policy %s)\n", result.DuplicatesRemoved, result.Duplicates)
+	}
 }
@@ -0,0 +1,77 @@
+// Handling of the "rx" subcommand, which assesses a receiving station rather
+// than a transmitter.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Entry point for "wspranalysis rx ...". args excludes the "rx" word.
+func runRxCommand(args []string) {
+	flags := flag.NewFlagSet("rx", flag.ExitOnError)
+	common := addCommonFlags(flags, 24*time.Hour)
+	iterations := flags.Int("bootstrap", wspr.DefaultBootstrapIterations, "Number of bootstrap `iterations` for the confidence interval of the aggregate metric (0 to disable)")
+	seed := flags.Uint64("seed", 1, "Seed for the bootstrap random number generator")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s rx [options] [receiver callsign] [band]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Assess [receiver callsign] on [band] by comparing the SNR it reported for each\n")
+		fmt.Fprintf(os.Stderr, "transmission with those reported by other receivers at a similar distance from the\n")
+		fmt.Fprintf(os.Stderr, "transmitter. The comparability options apply to receivers instead of transmitters;\n")
+		fmt.Fprintf(os.Stderr, "the exclusion and outlier options are not supported, and the power options have no\n")
+		fmt.Fprintf(os.Stderr, "effect since the reports compared are of the same transmission.\n\n")
+		fmt.Fprintf(os.Stderr, "[band] is one of:\n\t%v\n\n", wspr.BandNames())
+		fmt.Fprintf(os.Stderr, "Other options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return
+	}
+	target := wspr.NormaliseCallsign(flags.Arg(0))
+	band, err := wspr.BandNameToCode(flags.Arg(1))
+	if err != nil {
+		flags.Usage()
+		return
+	}
+	params, err := common.analysisParams(target, band)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if params.Exclusions != nil {
+		fmt.Fprintf(os.Stderr, "Error: exclusion lists are not supported by the rx subcommand\n")
+		return
+	}
+	if *iterations < 0 {
+		fmt.Fprintf(os.Stderr, "Error: number of bootstrap iterations must not be negative\n")
+		return
+	}
+	params.BootstrapIterations = *iterations
+	params.BootstrapSeed = *seed
+	source, err := common.reportSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	rxSource, ok := source.(wspr.RxReportSource)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: the report source does not support receiver analysis\n")
+		return
+	}
+
+	result, err := wspr.RunRxAnalysis(rxSource, params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := wspr.WriteRxResultText(os.Stdout, result, *common.verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
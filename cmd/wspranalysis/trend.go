@@ -0,0 +1,69 @@
+// Handling of the "trend" subcommand, which tracks the aggregate metric over
+// a long period.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jesse-/wspranalysis/pkg/wspr"
+)
+
+// Entry point for "wspranalysis trend ...". args excludes the "trend" word.
+func runTrendCommand(args []string) {
+	flags := flag.NewFlagSet("trend", flag.ExitOnError)
+	common := addCommonFlags(flags, 30*24*time.Hour)
+	bucket := flags.Duration("bucket", 24*time.Hour, "Length of each point of the trend (e.g., 24h, 6h)")
+	formatName := flags.String("format", "text", fmt.Sprintf("Output `format`, one of %v", wspr.OutputFormatNames()))
+	charts := flags.Bool("chart", false, "Draw a bar chart of the trend as well as a table (text format only)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s trend [options] [target callsign] [band]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Split a long period into buckets (daily by default) and calculate the aggregate metric\n")
+		fmt.Fprintf(os.Stderr, "for [target callsign] on [band] in each, to show how it changes over time.\n\n")
+		fmt.Fprintf(os.Stderr, "[band] is one of:\n\t%v\n\n", wspr.BandNames())
+		fmt.Fprintf(os.Stderr, "Other options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return
+	}
+	target := wspr.NormaliseCallsign(flags.Arg(0))
+	band, err := wspr.BandNameToCode(flags.Arg(1))
+	if err != nil {
+		flags.Usage()
+		return
+	}
+	params, err := common.analysisParams(target, band)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if *bucket <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: bucket length must be positive\n")
+		return
+	}
+	format, err := wspr.ParseOutputFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	source, err := common.reportSource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	result, err := wspr.RunTrend(source, params, *bucket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := wspr.WriteTrend(os.Stdout, result, format, wspr.TextOptions{Charts: *charts}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,160 @@
+// This file contains the strategies for combining the relative normalised
+// SNRs of all the report groups into the aggregate metric.
+
+package wspr
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AggregationStrategy selects how the samples of the report groups are
+// weighted when calculating the aggregate metric.
+type AggregationStrategy int
+
+const (
+	// The median of all the samples pooled together, so that groups with
+	// many comparable transmitters contribute many samples.
+	AggregatePooled AggregationStrategy = iota
+	// The median across groups of the median of each group.
+	AggregateGroupMedian
+	// The weighted median of all the samples, each weighted by the inverse of
+	// the number of samples in its group so that every group counts equally.
+	AggregateInverseCount
+	// The weighted median of all the samples, weighted so that every receiver
+	// counts equally however many groups and samples it has.
+	AggregatePerReceiver
+)
+
+// All the aggregation strategies, in the order in which they are reported.
+var aggregationStrategies = []AggregationStrategy{AggregatePooled, AggregateGroupMedian, AggregateInverseCount, AggregatePerReceiver}
+
+// Map between aggregation strategy names (as used on the command line) and
+// their AggregationStrategy values.
+var aggregationStrategyNames = map[string]AggregationStrategy{
+	"pooled":        AggregatePooled,
+	"group-median":  AggregateGroupMedian,
+	"inverse-count": AggregateInverseCount,
+	"per-receiver":  AggregatePerReceiver,
+}
+
+// Return all the aggregation strategy names (useful for the CLI help text).
+func AggregationStrategyNames() []string {
+	names := make([]string, 0, len(aggregationStrategyNames))
+	for k := range aggregationStrategyNames {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Convert an aggregation strategy name to its AggregationStrategy. Returns an
+// error if the name is not recognised.
+func ParseAggregationStrategy(name string) (AggregationStrategy, error) {
+	if strategy, ok := aggregationStrategyNames[strings.ToLower(name)]; ok {
+		return strategy, nil
+	}
+	return 0, fmt.Errorf("unrecognised aggregation strategy: %s", name)
+}
+
+func (s AggregationStrategy) String() string {
+	for name, strategy := range aggregationStrategyNames {
+		if strategy == s {
+			return name
+		}
+	}
+	return fmt.Sprintf("AggregationStrategy(%d)", int(s))
+}
+
+// The aggregate metric calculated with a particular strategy.
+type StrategyAggregate struct {
+	Strategy  AggregationStrategy
+	Aggregate AggregateMetric
+}
+
+// Calculate the median of values where each value has the given weight. If
+// the cumulative weight reaches exactly half the total at some value, the
+// result is the mean of that value and the next, as for an unweighted median.
+// values must not be empty.
+func weightedMedian(values, weights []float64) float64 {
+	order := make([]int, len(values))
+	var totalWeight float64
+	for i := range values {
+		order[i] = i
+		totalWeight += weights[i]
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(values[a], values[b])
+	})
+	const tolerance = 1e-9
+	var cumulativeWeight float64
+	for i, index := range order {
+		cumulativeWeight += weights[index]
+		if cumulativeWeight >= totalWeight/2-tolerance*totalWeight {
+			if cumulativeWeight <= totalWeight/2+tolerance*totalWeight && i+1 < len(order) {
+				return (values[index] + values[order[i+1]]) / 2
+			}
+			return values[index]
+		}
+	}
+	return values[order[len(order)-1]]
+}
+
+// Calculate the aggregate metric of groups using strategy. The confidence
+// interval is not calculated.
+func aggregateMetric(groups []GroupResult, strategy AggregationStrategy) AggregateMetric {
+	var metric AggregateMetric
+	groupsByReceiver := make(map[string]int)
+	for _, group := range groups {
+		metric.Samples += len(group.RelativeSnrNorms_dB)
+		groupsByReceiver[group.RxSign]++
+	}
+	if !metric.Valid() {
+		return metric
+	}
+	// The samples are negated so that positive values mean the target did
+	// better than the others.
+	var values, weights []float64
+	for _, group := range groups {
+		if len(group.RelativeSnrNorms_dB) == 0 {
+			continue
+		}
+		groupValues := make([]float64, len(group.RelativeSnrNorms_dB))
+		for i, relativeSnrNorm := range group.RelativeSnrNorms_dB {
+			groupValues[i] = -float64(relativeSnrNorm)
+		}
+		switch strategy {
+		case AggregateGroupMedian:
+			groupMedian, _ := median(groupValues, false)
+			values = append(values, groupMedian)
+		case AggregateInverseCount, AggregatePerReceiver:
+			weight := 1 / float64(len(groupValues))
+			if strategy == AggregatePerReceiver {
+				weight /= float64(groupsByReceiver[group.RxSign])
+			}
+			for _, value := range groupValues {
+				values = append(values, value)
+				weights = append(weights, weight)
+			}
+		default:
+			values = append(values, groupValues...)
+		}
+	}
+	if weights == nil {
+		metric.DbMedian, _ = median(values, false)
+	} else {
+		metric.DbMedian = weightedMedian(values, weights)
+	}
+	return metric
+}
+
+// Calculate the aggregate metric of groups with every strategy.
+func strategyAggregates(groups []GroupResult) []StrategyAggregate {
+	aggregates := make([]StrategyAggregate, len(aggregationStrategies))
+	for i, strategy := range aggregationStrategies {
+		aggregates[i] = StrategyAggregate{Strategy: strategy, Aggregate: aggregateMetric(groups, strategy)}
+	}
+	return aggregates
+}
@@ -0,0 +1,149 @@
+package wspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestParseAggregationStrategy tests that names and strategies round trip.
+func TestParseAggregationStrategy(t *testing.T) {
+	for _, name := range AggregationStrategyNames() {
+		strategy, err := ParseAggregationStrategy(strings.ToUpper(name))
+		if err != nil {
+			t.Fatalf("ParseAggregationStrategy(%q) unexpected error: %v", name, err)
+		}
+		if strategy.String() != name {
+			t.Errorf("ParseAggregationStrategy(%q).String() = %q", name, strategy.String())
+		}
+	}
+	if _, err := ParseAggregationStrategy("mean"); err == nil {
+		t.Errorf("ParseAggregationStrategy(\"mean\") expected error, got nil")
+	}
+}
+
+// TestWeightedMedian tests the weighted median, including the case in which
+// the weight is split evenly.
+func TestWeightedMedian(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []float64
+		weights []float64
+		want    float64
+	}{
+		{name: "equal weights odd", values: []float64{3, 1, 2}, weights: []float64{1, 1, 1}, want: 2},
+		{name: "equal weights even", values: []float64{4, 1, 2, 3}, weights: []float64{1, 1, 1, 1}, want: 2.5},
+		{name: "heavy value", values: []float64{1, 2, 3}, weights: []float64{0.1, 0.1, 5}, want: 3},
+		{name: "split evenly", values: []float64{1, 5}, weights: []float64{1.0 / 3, 1.0 / 3}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weightedMedian(tt.values, tt.weights); got != tt.want {
+				t.Errorf("weightedMedian() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Groups in which receiver R1 has a busy group and a quiet one and R2 has a
+// quiet one.
+var strategyTestGroups = []GroupResult{
+	{ReceptionReportGroup: ReceptionReportGroup{RxSign: "R1"}, RelativeSnrNorms_dB: []int8{-1, -1, -1, -1}},
+	{ReceptionReportGroup: ReceptionReportGroup{RxSign: "R1"}, RelativeSnrNorms_dB: []int8{-3}},
+	{ReceptionReportGroup: ReceptionReportGroup{RxSign: "R2"}, RelativeSnrNorms_dB: []int8{-5}},
+}
+
+// TestAggregateMetric tests each strategy.
+func TestAggregateMetric(t *testing.T) {
+	tests := []struct {
+		strategy AggregationStrategy
+		want     float64
+	}{
+		// Samples 1, 1, 1, 1, 3, 5.
+		{strategy: AggregatePooled, want: 1},
+		// Group medians 1, 3, 5.
+		{strategy: AggregateGroupMedian, want: 3},
+		// Weights 1/4 for each sample of the busy group, 1 for the others.
+		{strategy: AggregateInverseCount, want: 3},
+		// R1's groups share its weight, so half the weight is on 5.
+		{strategy: AggregatePerReceiver, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy.String(), func(t *testing.T) {
+			metric := aggregateMetric(strategyTestGroups, tt.strategy)
+			if !metric.Valid() || metric.Samples != 6 || metric.DbMedian != tt.want {
+				t.Errorf("aggregateMetric() = %+v, want %v from 6 samples", metric, tt.want)
+			}
+		})
+	}
+}
+
+// TestAggregateMetric_TooFewSamples tests that no metric is calculated from a
+// single sample.
+func TestAggregateMetric_TooFewSamples(t *testing.T) {
+	for _, strategy := range aggregationStrategies {
+		if metric := aggregateMetric(strategyTestGroups[2:], strategy); metric.Valid() {
+			t.Errorf("aggregateMetric(%v) = %+v, want invalid", strategy, metric)
+		}
+	}
+}
+
+// TestAnalyseReports_Strategies tests that the selected strategy gives the
+// headline metric and that all are reported.
+func TestAnalyseReports_Strategies(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign: "R1",
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 43, Snr_dB: -10},
+				{TxSign: "TX1", Power_dBm: 43, Snr_dB: -11},
+				{TxSign: "TX2", Power_dBm: 43, Snr_dB: -11},
+				{TxSign: "TX3", Power_dBm: 43, Snr_dB: -11},
+			},
+		},
+		{
+			RxSign: "R2",
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 43, Snr_dB: -10},
+				{TxSign: "TX1", Power_dBm: 43, Snr_dB: -15},
+			},
+		},
+	}
+	params := AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43, Aggregation: AggregateGroupMedian}
+
+	result := AnalyseReports(params, rxReports, nil)
+
+	// Group medians are 1 and 5.
+	if result.Aggregate.DbMedian != 3 {
+		t.Errorf("AnalyseReports() aggregate = %+v, want 3", result.Aggregate)
+	}
+	if len(result.StrategyAggregates) != len(aggregationStrategies) || result.StrategyAggregates[0].Aggregate.DbMedian != 1 {
+		t.Errorf("AnalyseReports() strategy aggregates = %+v, want pooled first with 1", result.StrategyAggregates)
+	}
+
+	var buf bytes.Buffer
+	WriteResultText(&buf, result, TextOptions{})
+	if !strings.Contains(buf.String(), "group-median     +3.0dBmedian (selected)") {
+		t.Errorf("WriteResultText() output does not show the selected strategy:\n%s", buf.String())
+	}
+	buf.Reset()
+	WriteResultJSON(&buf, result)
+	var doc struct {
+		Aggregate struct {
+			Strategy string `json:"strategy"`
+		} `json:"aggregate"`
+		Strategies []struct {
+			Strategy string   `json:"strategy"`
+			DbMedian *float64 `json:"db_median"`
+		} `json:"strategies"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+	}
+	if doc.Aggregate.Strategy != "group-median" || len(doc.Strategies) != 4 || doc.Strategies[3].Strategy != "per-receiver" || doc.Strategies[3].DbMedian == nil {
+		t.Errorf("WriteResultJSON() strategies = %+v, aggregate = %+v", doc.Strategies, doc.Aggregate)
+	}
+}
@@ -0,0 +1,105 @@
+// This file contains the bootstrap resampling used to put confidence
+// intervals on the aggregate metric.
+
+package wspr
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// Default number of bootstrap resamples.
+const DefaultBootstrapIterations = 2000
+
+// Collect the aggregate metric samples (negated relative normalised SNRs) of
+// all the groups into one slice.
+func pooledSamples(groups []GroupResult) []float64 {
+	var samples []float64
+	for _, group := range groups {
+		for _, relativeSnrNorm := range group.RelativeSnrNorms_dB {
+			samples = append(samples, -float64(relativeSnrNorm))
+		}
+	}
+	return samples
+}
+
+// Calculate the aggregate metric of a bootstrap resample of groups using
+// strategy. Whole groups are resampled with replacement, rather than
+// individual samples, because the samples within a group are correlated. The
+// second return value is false if the resample has too few samples for the
+// metric to be valid. resample is scratch space which is reused between
+// calls.
+func resampledAggregate(groups []GroupResult, strategy AggregationStrategy, rng *rand.Rand, resample []GroupResult) (float64, []GroupResult, bool) {
+	resample = resample[:0]
+	for range groups {
+		resample = append(resample, groups[rng.IntN(len(groups))])
+	}
+	metric := aggregateMetric(resample, strategy)
+	return metric.DbMedian, resample, metric.Valid()
+}
+
+// Build a percentile confidence interval from the statistics calculated for
+// each bootstrap resample. The interval is invalid if fewer than half of the
+// requested iterations gave a valid statistic.
+// NOTE: statistics will be sorted in place.
+func percentileInterval(statistics []float64, iterations int, level float64) ConfidenceInterval {
+	if len(statistics) == 0 || len(statistics) < iterations/2 {
+		return ConfidenceInterval{}
+	}
+	slices.Sort(statistics)
+	alpha := (1 - level) / 2
+	return ConfidenceInterval{
+		Low:   quantile(statistics, alpha),
+		High:  quantile(statistics, 1-alpha),
+		Level: level,
+		Valid: true,
+	}
+}
+
+// Create the random number generator for bootstrap resampling. A fixed seed
+// makes the results reproducible.
+func newBootstrapRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// Calculate a bootstrap confidence interval for the aggregate metric of a set
+// of groups calculated with strategy.
+func bootstrapAggregate(groups []GroupResult, strategy AggregationStrategy, iterations int, seed uint64) ConfidenceInterval {
+	if len(groups) == 0 || iterations <= 0 {
+		return ConfidenceInterval{}
+	}
+	rng := newBootstrapRand(seed)
+	var resample []GroupResult
+	metrics := make([]float64, 0, iterations)
+	for range iterations {
+		var metric float64
+		var valid bool
+		metric, resample, valid = resampledAggregate(groups, strategy, rng, resample)
+		if valid {
+			metrics = append(metrics, metric)
+		}
+	}
+	return percentileInterval(metrics, iterations, ConfidenceLevel)
+}
+
+// Calculate a bootstrap confidence interval for the difference between the
+// aggregate metrics of two sets of groups (after minus before) calculated
+// with strategy.
+func bootstrapShift(before, after []GroupResult, strategy AggregationStrategy, iterations int, seed uint64) ConfidenceInterval {
+	if len(before) == 0 || len(after) == 0 || iterations <= 0 {
+		return ConfidenceInterval{}
+	}
+	rng := newBootstrapRand(seed)
+	var beforeSamples, afterSamples []GroupResult
+	shifts := make([]float64, 0, iterations)
+	for range iterations {
+		var beforeMetric, afterMetric float64
+		var beforeValid, afterValid bool
+		beforeMetric, beforeSamples, beforeValid = resampledAggregate(before, strategy, rng, beforeSamples)
+		afterMetric, afterSamples, afterValid = resampledAggregate(after, strategy, rng, afterSamples)
+		if beforeValid && afterValid {
+			shifts = append(shifts, afterMetric-beforeMetric)
+		}
+	}
+	return percentileInterval(shifts, iterations, ConfidenceLevel)
+}
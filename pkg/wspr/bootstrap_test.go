@@ -0,0 +1,83 @@
+package wspr
+
+import (
+	"testing"
+)
+
+// relativeGroup creates a GroupResult with the given relative normalised SNRs.
+func relativeGroup(relativeSnrNorms ...int8) GroupResult {
+	return GroupResult{RelativeSnrNorms_dB: relativeSnrNorms}
+}
+
+// TestPooledSamples tests that the samples are negated and pooled.
+func TestPooledSamples(t *testing.T) {
+	samples := pooledSamples([]GroupResult{relativeGroup(-5, 2), relativeGroup(3)})
+
+	want := []float64{5, -2, -3}
+	if len(samples) != len(want) {
+		t.Fatalf("pooledSamples() = %v, want %v", samples, want)
+	}
+	for i := range want {
+		if samples[i] != want[i] {
+			t.Errorf("pooledSamples() = %v, want %v", samples, want)
+			break
+		}
+	}
+}
+
+// TestBootstrapShift tests the bootstrap confidence interval of a shift.
+func TestBootstrapShift(t *testing.T) {
+	before := []GroupResult{relativeGroup(-2, -3), relativeGroup(-1, -2), relativeGroup(-3, -2)}
+	after := []GroupResult{relativeGroup(-6, -5), relativeGroup(-4, -5), relativeGroup(-5, -6)}
+
+	ci := bootstrapShift(before, after, AggregatePooled, 500, 1)
+
+	if !ci.Valid {
+		t.Fatalf("bootstrapShift() CI invalid")
+	}
+	// Every resample shift lies between 1dB and 5dB.
+	if ci.Low < 1 || ci.High > 5 || ci.Low > 3 || ci.High < 3 {
+		t.Errorf("bootstrapShift() = [%v, %v], want within [1, 5] and containing 3", ci.Low, ci.High)
+	}
+	if again := bootstrapShift(before, after, AggregatePooled, 500, 1); again != ci {
+		t.Errorf("bootstrapShift() not reproducible with the same seed: %v then %v", ci, again)
+	}
+}
+
+// TestBootstrapShift_Invalid tests that the interval is invalid without data
+// or iterations.
+func TestBootstrapShift_Invalid(t *testing.T) {
+	groups := []GroupResult{relativeGroup(-1, -2)}
+	if ci := bootstrapShift(nil, groups, AggregatePooled, 100, 1); ci.Valid {
+		t.Errorf("bootstrapShift() with no groups before is valid")
+	}
+	if ci := bootstrapShift(groups, groups, AggregatePooled, 0, 1); ci.Valid {
+		t.Errorf("bootstrapShift() with no iterations is valid")
+	}
+	// A single sample per resample never gives a valid metric.
+	if ci := bootstrapShift([]GroupResult{relativeGroup(-1)}, groups, AggregatePooled, 100, 1); ci.Valid {
+		t.Errorf("bootstrapShift() with too few samples is valid")
+	}
+}
+
+// TestBootstrapAggregate tests the bootstrap confidence interval of the
+// aggregate metric.
+func TestBootstrapAggregate(t *testing.T) {
+	groups := []GroupResult{relativeGroup(-2, -3), relativeGroup(-1, -2), relativeGroup(-4, -3), relativeGroup(-2, -2)}
+
+	ci := bootstrapAggregate(groups, AggregatePooled, 500, 7)
+
+	if !ci.Valid || ci.Level != ConfidenceLevel {
+		t.Fatalf("bootstrapAggregate() = %+v, want valid at %v", ci, ConfidenceLevel)
+	}
+	// Every resample metric lies between 1dB and 4dB.
+	if ci.Low < 1 || ci.High > 4 || ci.Low > ci.High {
+		t.Errorf("bootstrapAggregate() = [%v, %v], want within [1, 4]", ci.Low, ci.High)
+	}
+	if again := bootstrapAggregate(groups, AggregatePooled, 500, 7); again != ci {
+		t.Errorf("bootstrapAggregate() not reproducible with the same seed: %v then %v", ci, again)
+	}
+	if ci := bootstrapAggregate(groups, AggregatePooled, 0, 7); ci.Valid {
+		t.Errorf("bootstrapAggregate() with no iterations is valid")
+	}
+}
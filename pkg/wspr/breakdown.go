@@ -0,0 +1,149 @@
+// This file contains breakdowns of the analysis results into bins (e.g. by
+// direction), to show how the target's performance varies.
+
+package wspr
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Statistics for the samples falling in one bin of a Breakdown. The bin covers
+// values from Low (inclusive) to High (exclusive), in units which depend on
+// the kind of breakdown. Median_dB and IQR_dB are only meaningful if Samples
+// is non-zero.
+type BreakdownBin struct {
+	Label     string
+	Low       float64
+	High      float64
+	Median_dB float64
+	IQR_dB    float64
+	Samples   int
+}
+
+// A breakdown of the results into bins. Name describes what the results are
+// binned by and Metric describes what the samples in each bin are.
+type Breakdown struct {
+	Name   string
+	Metric string
+	Bins   []BreakdownBin
+}
+
+// Fill in the statistics of a bin from its samples.
+func (bin *BreakdownBin) setSamples(samples []float64) {
+	bin.Samples = len(samples)
+	if len(samples) == 0 {
+		return
+	}
+	slices.Sort(samples)
+	bin.Median_dB = quantile(samples, 0.5)
+	bin.IQR_dB = quantile(samples, 0.75) - quantile(samples, 0.25)
+}
+
+// Names of the 16 points of the compass, starting from north.
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// Narrowest azimuth sector allowed by AzimuthBreakdown. Azimuths are reported
+// in whole degrees, so narrower sectors would only add empty bins.
+const MinSectorWidth_deg = 1.0
+
+// AzimuthBreakdown bins the target's dB-over-median figure for each report
+// group by the bearing of the receiver from the target. The sectors are
+// centred on north and are sectorWidth_deg wide (at least MinSectorWidth_deg,
+// and rounded so that a whole number of sectors fits in 360º).
+func AzimuthBreakdown(groups []GroupResult, sectorWidth_deg float64) Breakdown {
+	numSectors := max(int(math.Round(360/max(sectorWidth_deg, MinSectorWidth_deg))), 1)
+	width := 360 / float64(numSectors)
+	samples := make([][]float64, numSectors)
+	for _, group := range groups {
+		azimuth := float64(group.Reports[group.TargetIndex].TxAzimuth)
+		sector := int(math.Floor((azimuth+width/2)/width)) % numSectors
+		samples[sector] = append(samples[sector], float64(group.SnrNormOverMedian_dB))
+	}
+	breakdown := Breakdown{Name: "azimuth", Metric: "dB over median", Bins: make([]BreakdownBin, numSectors)}
+	for i := range breakdown.Bins {
+		bin := &breakdown.Bins[i]
+		centre := float64(i) * width
+		bin.Low = math.Mod(centre-width/2+360, 360)
+		bin.High = centre + width/2
+		bin.Label = fmt.Sprintf("%gº", centre)
+		if len(compassPoints)%numSectors == 0 {
+			bin.Label = fmt.Sprintf("%s (%gº)", compassPoints[i*len(compassPoints)/numSectors], centre)
+		}
+		bin.setSamples(samples[i])
+	}
+	return breakdown
+}
+
+// Default lower bounds of the distance bands for DistanceBreakdown, roughly
+// separating NVIS, short skip, medium and long distance paths.
+var DefaultDistanceBands_km = []float64{0, 500, 2000, 6000}
+
+// DistanceBreakdown bins the relative normalised SNRs of every group (negated,
+// so that each bin's median is the aggregate dBmedian metric restricted to
+// that bin) by the distance between the target and the receiver.
+// lowerBounds_km holds the start of each distance band in ascending order;
+// the last band is unbounded. Targets closer than the first bound are ignored.
+func DistanceBreakdown(groups []GroupResult, lowerBounds_km []float64) Breakdown {
+	samples := make([][]float64, len(lowerBounds_km))
+	for _, group := range groups {
+		distance := float64(group.Reports[group.TargetIndex].Distance_km)
+		band := -1
+		for i, lowerBound := range lowerBounds_km {
+			if distance >= lowerBound {
+				band = i
+			}
+		}
+		if band == -1 {
+			continue
+		}
+		for _, relativeSnrNorm := range group.RelativeSnrNorms_dB {
+			samples[band] = append(samples[band], -float64(relativeSnrNorm))
+		}
+	}
+	breakdown := Breakdown{Name: "distance", Metric: "dBmedian", Bins: make([]BreakdownBin, len(lowerBounds_km))}
+	for i := range breakdown.Bins {
+		bin := &breakdown.Bins[i]
+		bin.Low = lowerBounds_km[i]
+		if i+1 < len(lowerBounds_km) {
+			bin.High = lowerBounds_km[i+1]
+			bin.Label = fmt.Sprintf("%g-%gkm", bin.Low, bin.High)
+		} else {
+			bin.High = math.Inf(1)
+			bin.Label = fmt.Sprintf("%g+km", bin.Low)
+		}
+		bin.setSamples(samples[i])
+	}
+	return breakdown
+}
+
+// SolarTimeOffset returns the offset of local mean solar time from UTC at the
+// given longitude (positive east).
+func SolarTimeOffset(longitude_deg float64) time.Duration {
+	return time.Duration(longitude_deg / 15 * float64(time.Hour))
+}
+
+// HourlyBreakdown bins the target's dB-over-median figure for each report
+// group by hour of the day. The hours are in UTC shifted by utcOffset (e.g.
+// from SolarTimeOffset); timeName describes the resulting time scale.
+func HourlyBreakdown(groups []GroupResult, utcOffset time.Duration, timeName string) Breakdown {
+	samples := make([][]float64, 24)
+	for _, group := range groups {
+		hour := group.Time.UTC().Add(utcOffset).Hour()
+		samples[hour] = append(samples[hour], float64(group.SnrNormOverMedian_dB))
+	}
+	breakdown := Breakdown{Name: fmt.Sprintf("hour (%s)", timeName), Metric: "dB over median", Bins: make([]BreakdownBin, 24)}
+	for i := range breakdown.Bins {
+		bin := &breakdown.Bins[i]
+		bin.Low = float64(i)
+		bin.High = float64(i + 1)
+		bin.Label = fmt.Sprintf("%02d:00", i)
+		bin.setSamples(samples[i])
+	}
+	return breakdown
+}
@@ -0,0 +1,212 @@
+package wspr
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Build a GroupResult whose target has the given azimuth and dB over median.
+func azimuthGroup(azimuth uint16, overMedian int8) GroupResult {
+	return GroupResult{
+		ReceptionReportGroup: ReceptionReportGroup{
+			Reports: []ReceptionReport{{TxSign: "W5XYZ", TxAzimuth: azimuth}},
+		},
+		SnrNormOverMedian_dB: overMedian,
+	}
+}
+
+// TestAzimuthBreakdown tests binning into 16 compass sectors.
+func TestAzimuthBreakdown(t *testing.T) {
+	groups := []GroupResult{
+		azimuthGroup(0, 2),
+		azimuthGroup(355, 4), // Wraps round into the north sector.
+		azimuthGroup(11, 6),
+		azimuthGroup(12, -3), // Just into NNE.
+		azimuthGroup(180, 1),
+	}
+
+	breakdown := AzimuthBreakdown(groups, 22.5)
+
+	if len(breakdown.Bins) != 16 {
+		t.Fatalf("AzimuthBreakdown() returned %d bins, want 16", len(breakdown.Bins))
+	}
+	north := breakdown.Bins[0]
+	if north.Label != "N (0º)" || north.Samples != 3 || north.Median_dB != 4 || north.IQR_dB != 2 {
+		t.Errorf("AzimuthBreakdown() north bin = %+v", north)
+	}
+	if north.Low != 348.75 || north.High != 11.25 {
+		t.Errorf("AzimuthBreakdown() north bin covers [%v, %v), want [348.75, 11.25)", north.Low, north.High)
+	}
+	if nne := breakdown.Bins[1]; nne.Label != "NNE (22.5º)" || nne.Samples != 1 || nne.Median_dB != -3 {
+		t.Errorf("AzimuthBreakdown() NNE bin = %+v", nne)
+	}
+	if south := breakdown.Bins[8]; south.Label != "S (180º)" || south.Samples != 1 {
+		t.Errorf("AzimuthBreakdown() S bin = %+v", south)
+	}
+	if empty := breakdown.Bins[4]; empty.Samples != 0 {
+		t.Errorf("AzimuthBreakdown() E bin = %+v, want empty", empty)
+	}
+}
+
+// TestAzimuthBreakdown_CustomWidth tests sector widths which don't match compass points.
+func TestAzimuthBreakdown_CustomWidth(t *testing.T) {
+	breakdown := AzimuthBreakdown([]GroupResult{azimuthGroup(100, 1)}, 60)
+
+	if len(breakdown.Bins) != 6 {
+		t.Fatalf("AzimuthBreakdown() returned %d bins, want 6", len(breakdown.Bins))
+	}
+	if bin := breakdown.Bins[2]; bin.Label != "120º" || bin.Samples != 1 {
+		t.Errorf("AzimuthBreakdown() third bin = %+v", bin)
+	}
+}
+
+// TestAzimuthBreakdown_MinWidth tests that very narrow sectors are widened to
+// MinSectorWidth_deg.
+func TestAzimuthBreakdown_MinWidth(t *testing.T) {
+	breakdown := AzimuthBreakdown([]GroupResult{azimuthGroup(100, 1)}, 0.0001)
+
+	if len(breakdown.Bins) != 360 {
+		t.Errorf("AzimuthBreakdown() returned %d bins, want 360", len(breakdown.Bins))
+	}
+}
+
+// TestAzimuthBreakdown_FromAnalyseReports tests that AnalyseReports adds the breakdown when requested.
+func TestAzimuthBreakdown_FromAnalyseReports(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, TxAzimuth: 90},
+				{TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -14},
+			},
+		},
+	}
+
+	result := AnalyseReports(AnalysisParams{NormTxPwr_dBm: 43}, rxReports, nil)
+	if len(result.Breakdowns) != 0 {
+		t.Errorf("AnalyseReports() returned %d breakdowns, want none", len(result.Breakdowns))
+	}
+
+	result = AnalyseReports(AnalysisParams{NormTxPwr_dBm: 43, AzimuthSectorWidth_deg: 90}, rxReports, nil)
+	if len(result.Breakdowns) != 1 {
+		t.Fatalf("AnalyseReports() returned %d breakdowns, want 1", len(result.Breakdowns))
+	}
+	if bin := result.Breakdowns[0].Bins[1]; bin.Label != "E (90º)" || bin.Samples != 1 || bin.Median_dB != 2 {
+		t.Errorf("AnalyseReports() E bin = %+v", bin)
+	}
+}
+
+// TestDistanceBreakdown tests binning of relative SNRs by target distance.
+func TestDistanceBreakdown(t *testing.T) {
+	groups := []GroupResult{
+		{
+			ReceptionReportGroup: ReceptionReportGroup{Reports: []ReceptionReport{{TxSign: "W5XYZ", Distance_km: 300}}},
+			RelativeSnrNorms_dB:  []int8{-2, -4, 6},
+		},
+		{
+			ReceptionReportGroup: ReceptionReportGroup{Reports: []ReceptionReport{{TxSign: "W5XYZ", Distance_km: 500}}},
+			RelativeSnrNorms_dB:  []int8{3},
+		},
+		{
+			ReceptionReportGroup: ReceptionReportGroup{Reports: []ReceptionReport{{TxSign: "W5XYZ", Distance_km: 9000}}},
+			RelativeSnrNorms_dB:  []int8{1, 5},
+		},
+	}
+
+	breakdown := DistanceBreakdown(groups, DefaultDistanceBands_km)
+
+	if len(breakdown.Bins) != 4 {
+		t.Fatalf("DistanceBreakdown() returned %d bins, want 4", len(breakdown.Bins))
+	}
+	wants := []struct {
+		label   string
+		samples int
+		median  float64
+	}{
+		{label: "0-500km", samples: 3, median: 2},
+		{label: "500-2000km", samples: 1, median: -3},
+		{label: "2000-6000km", samples: 0},
+		{label: "6000+km", samples: 2, median: -3},
+	}
+	for i, want := range wants {
+		bin := breakdown.Bins[i]
+		if bin.Label != want.label || bin.Samples != want.samples || (want.samples > 0 && bin.Median_dB != want.median) {
+			t.Errorf("DistanceBreakdown() bin %d = %+v, want %+v", i, bin, want)
+		}
+	}
+	if !math.IsInf(breakdown.Bins[3].High, 1) {
+		t.Errorf("DistanceBreakdown() last bin High = %v, want +Inf", breakdown.Bins[3].High)
+	}
+}
+
+// TestDistanceBreakdown_BelowFirstBound tests that targets closer than the first band are ignored.
+func TestDistanceBreakdown_BelowFirstBound(t *testing.T) {
+	groups := []GroupResult{
+		{
+			ReceptionReportGroup: ReceptionReportGroup{Reports: []ReceptionReport{{TxSign: "W5XYZ", Distance_km: 50}}},
+			RelativeSnrNorms_dB:  []int8{1},
+		},
+	}
+
+	breakdown := DistanceBreakdown(groups, []float64{100, 1000})
+
+	for _, bin := range breakdown.Bins {
+		if bin.Samples != 0 {
+			t.Errorf("DistanceBreakdown() bin %s has %d samples, want 0", bin.Label, bin.Samples)
+		}
+	}
+}
+
+// TestSolarTimeOffset tests the SolarTimeOffset function.
+func TestSolarTimeOffset(t *testing.T) {
+	tests := []struct {
+		longitude float64
+		want      time.Duration
+	}{
+		{longitude: 0, want: 0},
+		{longitude: 15, want: time.Hour},
+		{longitude: -97.5, want: -6*time.Hour - 30*time.Minute},
+	}
+
+	for _, tt := range tests {
+		if result := SolarTimeOffset(tt.longitude); result != tt.want {
+			t.Errorf("SolarTimeOffset(%v) = %v, want %v", tt.longitude, result, tt.want)
+		}
+	}
+}
+
+// TestHourlyBreakdown tests binning by hour in UTC and shifted time.
+func TestHourlyBreakdown(t *testing.T) {
+	hourGroup := func(hour, minute int, overMedian int8) GroupResult {
+		return GroupResult{
+			ReceptionReportGroup: ReceptionReportGroup{Time: time.Date(2024, 12, 14, hour, minute, 0, 0, time.UTC)},
+			SnrNormOverMedian_dB: overMedian,
+		}
+	}
+	groups := []GroupResult{
+		hourGroup(0, 10, 3),
+		hourGroup(0, 50, 5),
+		hourGroup(23, 58, -1),
+	}
+
+	breakdown := HourlyBreakdown(groups, 0, "UTC")
+
+	if len(breakdown.Bins) != 24 || breakdown.Name != "hour (UTC)" {
+		t.Fatalf("HourlyBreakdown() = %s with %d bins, want hour (UTC) with 24", breakdown.Name, len(breakdown.Bins))
+	}
+	if bin := breakdown.Bins[0]; bin.Label != "00:00" || bin.Samples != 2 || bin.Median_dB != 4 {
+		t.Errorf("HourlyBreakdown() first bin = %+v", bin)
+	}
+	if bin := breakdown.Bins[23]; bin.Samples != 1 || bin.Median_dB != -1 {
+		t.Errorf("HourlyBreakdown() last bin = %+v", bin)
+	}
+
+	// Half an hour ahead of UTC, the 00:50 and 23:58 groups move to the next hour.
+	breakdown = HourlyBreakdown(groups, 30*time.Minute, "solar time")
+	if breakdown.Bins[0].Samples != 2 || breakdown.Bins[1].Samples != 1 || breakdown.Bins[23].Samples != 0 {
+		t.Errorf("HourlyBreakdown() with offset has %d, %d and %d samples in hours 0, 1 and 23, want 2, 1 and 0",
+			breakdown.Bins[0].Samples, breakdown.Bins[1].Samples, breakdown.Bins[23].Samples)
+	}
+}
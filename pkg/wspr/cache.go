@@ -0,0 +1,241 @@
+// This file contains a ReportSource which caches the results of another
+// ReportSource on disk, so that repeated analyses of overlapping time ranges
+// only fetch the data which has not been seen before.
+
+package wspr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The cache splits every request into chunks of this length, aligned to UTC.
+// Each chunk is stored in its own file.
+const cacheChunkDuration = time.Hour
+
+// Chunks which end less than this long ago are not cached, because spots
+// are still being uploaded to the database for some time after they occur.
+const cacheSettleTime = time.Hour
+
+// Included in every cache key. Bump this whenever the fields of
+// ReceptionReport or the spots matched for a target change so that stale
+// entries are ignored.
+const cacheFormatVersion = 4
+
+// Suffix of cache entry files.
+const cacheFileSuffix = ".json"
+
+// CachingSource is a ReportSource which stores the reports returned by
+// Upstream in Dir, keyed by target, band and time chunk. On subsequent calls
+// only the chunks which are not already cached are fetched from Upstream.
+type CachingSource struct {
+	Upstream ReportSource
+	Dir      string
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// DefaultCacheDir returns the default location of the report cache within
+// the user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine user cache directory (%w)", err)
+	}
+	return filepath.Join(dir, "wspranalysis"), nil
+}
+
+// FetchReports implements ReportSource.
+func (s *CachingSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	return s.fetch(target.String(), band, startTime, duration, func(start time.Time, duration time.Duration) ([]ReceptionReport, error) {
+		return s.Upstream.FetchReports(target, band, start, duration)
+	})
+}
+
+// FetchRxReports implements RxReportSource. It returns an error if Upstream
+// does not implement RxReportSource.
+func (s *CachingSource) FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	upstream, ok := s.Upstream.(RxReportSource)
+	if !ok {
+		return nil, fmt.Errorf("report source does not support receiver-perspective queries")
+	}
+	// Callsigns cannot contain ':', so the prefix keeps these entries apart
+	// from those of FetchReports.
+	return s.fetch("rx:"+target.String(), band, startTime, duration, func(start time.Time, duration time.Duration) ([]ReceptionReport, error) {
+		return upstream.FetchRxReports(target, band, start, duration)
+	})
+}
+
+// Shared implementation of FetchReports and FetchRxReports. key identifies
+// the query in the cache and upstream fetches the reports for a time range
+// which is not cached.
+func (s *CachingSource) fetch(key string, band int, startTime time.Time, duration time.Duration,
+	upstream func(start time.Time, duration time.Duration) ([]ReceptionReport, error)) ([]ReceptionReport, error) {
+	endTime := startTime.Add(duration)
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	// Work out which chunks cover the requested time range and load the ones
+	// which are already cached.
+	var chunkStarts []time.Time
+	for t := startTime.UTC().Truncate(cacheChunkDuration); t.Before(endTime); t = t.Add(cacheChunkDuration) {
+		chunkStarts = append(chunkStarts, t)
+	}
+	chunks := make([][]ReceptionReport, len(chunkStarts))
+	cached := make([]bool, len(chunkStarts))
+	for i, chunkStart := range chunkStarts {
+		chunks[i], cached[i] = s.load(key, band, chunkStart)
+	}
+	// Fetch each run of consecutive missing chunks with a single upstream
+	// request, then split the result back into chunks.
+	for i := 0; i < len(chunkStarts); {
+		if cached[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(chunkStarts) && !cached[j] {
+			j++
+		}
+		runStart := chunkStarts[i]
+		reports, err := upstream(runStart, time.Duration(j-i)*cacheChunkDuration)
+		if err != nil {
+			return nil, err
+		}
+		for _, report := range reports {
+			k := i + int(report.Time().Sub(runStart)/cacheChunkDuration)
+			if k >= i && k < j {
+				chunks[k] = append(chunks[k], report)
+			}
+		}
+		for k := i; k < j; k++ {
+			if chunkStarts[k].Add(cacheChunkDuration).Add(cacheSettleTime).After(now) {
+				continue
+			}
+			if err := s.store(key, band, chunkStarts[k], chunks[k]); err != nil {
+				return nil, err
+			}
+		}
+		i = j
+	}
+	// Concatenate the chunks (which are in time order) and trim the result to
+	// the requested time range.
+	var result []ReceptionReport
+	for _, chunk := range chunks {
+		for _, report := range chunk {
+			if t := report.Time(); !t.Before(startTime) && t.Before(endTime) {
+				result = append(result, report)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Build the path of the cache file for a chunk. The file name is a hash of
+// everything which determines the chunk's contents.
+func (s *CachingSource) chunkPath(key string, band int, chunkStart time.Time) string {
+	key = fmt.Sprintf("v%d|%s|%d|%d|%d", cacheFormatVersion, key, band,
+		chunkStart.Unix(), int64(cacheChunkDuration/time.Second))
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+cacheFileSuffix)
+}
+
+// Load a chunk from the cache. The second return value is false if the chunk
+// is not cached (or cannot be read, in which case it will be refetched).
+func (s *CachingSource) load(key string, band int, chunkStart time.Time) ([]ReceptionReport, bool) {
+	path := s.chunkPath(key, band, chunkStart)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var reports []ReceptionReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, false
+	}
+	// Record the access time so that PruneCache keeps recently used entries.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return reports, true
+}
+
+// Store a chunk in the cache. The file is written atomically so that an
+// interrupted run never leaves a truncated entry behind.
+func (s *CachingSource) store(key string, band int, chunkStart time.Time, reports []ReceptionReport) error {
+	if reports == nil {
+		reports = []ReceptionReport{}
+	}
+	data, err := json.Marshal(reports)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry (%w)", err)
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory (%w)", err)
+	}
+	tmp, err := os.CreateTemp(s.Dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry (%w)", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry (%w)", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry (%w)", err)
+	}
+	if err := os.Rename(tmp.Name(), s.chunkPath(key, band, chunkStart)); err != nil {
+		return fmt.Errorf("failed to write cache entry (%w)", err)
+	}
+	return nil
+}
+
+// Report whether name is the name of a cache entry file (see chunkPath): a
+// lower case hex SHA-256 followed by cacheFileSuffix.
+func isCacheEntryName(name string) bool {
+	hash, ok := strings.CutSuffix(name, cacheFileSuffix)
+	if !ok || len(hash) != 2*sha256.Size {
+		return false
+	}
+	return strings.Trim(hash, "0123456789abcdef") == ""
+}
+
+// PruneCache removes the entries in the cache directory dir which have not
+// been used for at least maxAge (all entries if maxAge is zero). It returns
+// the number of entries removed. Other files in dir are left alone. A missing
+// cache directory is not an error.
+func PruneCache(dir string, maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory (%w)", err)
+	}
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isCacheEntryName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if maxAge == 0 || info.ModTime().Before(cutoff) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return removed, fmt.Errorf("failed to remove cache entry (%w)", err)
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,212 @@
+package wspr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSource is a ReportSource which generates one report per receiver
+// slot every ten minutes and records the time ranges it was asked for.
+type recordingSource struct {
+	requests [][2]time.Time
+}
+
+func (r *recordingSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	r.requests = append(r.requests, [2]time.Time{startTime, startTime.Add(duration)})
+	var reports []ReceptionReport
+	for t := startTime; t.Before(startTime.Add(duration)); t = t.Add(10 * time.Minute) {
+		reports = append(reports, ReceptionReport{TimeStr: t.UTC().Format(time.DateTime), RxSign: "W5ABC", TxSign: target.Callsign})
+	}
+	return reports, nil
+}
+
+// TestCachingSource_FetchesOnlyMissingChunks tests that cached chunks are not refetched.
+func TestCachingSource_FetchesOnlyMissingChunks(t *testing.T) {
+	upstream := &recordingSource{}
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	source := &CachingSource{Upstream: upstream, Dir: t.TempDir(), Now: func() time.Time { return now }}
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+
+	result, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart.Add(time.Hour), 2*time.Hour)
+	if err != nil {
+		t.Fatalf("FetchReports() unexpected error: %v", err)
+	}
+	if len(result) != 12 {
+		t.Errorf("FetchReports() returned %d reports, want 12", len(result))
+	}
+	if len(upstream.requests) != 1 {
+		t.Fatalf("FetchReports() made %d upstream requests, want 1", len(upstream.requests))
+	}
+
+	// A wider window should only fetch the hours either side of the cached ones.
+	upstream.requests = nil
+	result, err = source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, 4*time.Hour)
+	if err != nil {
+		t.Fatalf("FetchReports() unexpected error: %v", err)
+	}
+	if len(result) != 24 {
+		t.Errorf("FetchReports() returned %d reports, want 24", len(result))
+	}
+	want := [][2]time.Time{
+		{tStart, tStart.Add(time.Hour)},
+		{tStart.Add(3 * time.Hour), tStart.Add(4 * time.Hour)},
+	}
+	if len(upstream.requests) != len(want) {
+		t.Fatalf("FetchReports() made %d upstream requests, want %d", len(upstream.requests), len(want))
+	}
+	for i := range want {
+		if !upstream.requests[i][0].Equal(want[i][0]) || !upstream.requests[i][1].Equal(want[i][1]) {
+			t.Errorf("upstream request %d = %v, want %v", i, upstream.requests[i], want[i])
+		}
+	}
+	for i := 1; i < len(result); i++ {
+		if result[i].TimeStr < result[i-1].TimeStr {
+			t.Errorf("FetchReports() reports not ordered by time")
+		}
+	}
+}
+
+// TestCachingSource_TrimsToWindow tests that unaligned windows are trimmed.
+func TestCachingSource_TrimsToWindow(t *testing.T) {
+	upstream := &recordingSource{}
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	source := &CachingSource{Upstream: upstream, Dir: t.TempDir(), Now: func() time.Time { return now }}
+	tStart := time.Date(2024, 12, 14, 0, 30, 0, 0, time.UTC)
+
+	result, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour)
+	if err != nil {
+		t.Fatalf("FetchReports() unexpected error: %v", err)
+	}
+	if len(result) != 6 {
+		t.Errorf("FetchReports() returned %d reports, want 6", len(result))
+	}
+	if result[0].TimeStr != "2024-12-14 00:30:00" {
+		t.Errorf("FetchReports() first report at %s, want 2024-12-14 00:30:00", result[0].TimeStr)
+	}
+}
+
+// TestCachingSource_RecentChunksNotCached tests that chunks which may still change are refetched.
+func TestCachingSource_RecentChunksNotCached(t *testing.T) {
+	upstream := &recordingSource{}
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+	now := tStart.Add(90 * time.Minute)
+	source := &CachingSource{Upstream: upstream, Dir: t.TempDir(), Now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		if _, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour); err != nil {
+			t.Fatalf("FetchReports() unexpected error: %v", err)
+		}
+	}
+	if len(upstream.requests) != 2 {
+		t.Errorf("FetchReports() made %d upstream requests, want 2", len(upstream.requests))
+	}
+}
+
+// TestCachingSource_KeyedByTargetAndBand tests that different targets and bands use different entries.
+func TestCachingSource_KeyedByTargetAndBand(t *testing.T) {
+	upstream := &recordingSource{}
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	source := &CachingSource{Upstream: upstream, Dir: t.TempDir(), Now: func() time.Time { return now }}
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+
+	source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour)
+	source.FetchReports(ExactCallsign("N0OTH"), 14, tStart, time.Hour)
+	source.FetchReports(ExactCallsign("W5XYZ"), 7, tStart, time.Hour)
+	source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour)
+
+	if len(upstream.requests) != 3 {
+		t.Errorf("FetchReports() made %d upstream requests, want 3", len(upstream.requests))
+	}
+}
+
+// TestPruneCache tests that PruneCache removes only old entries.
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, strings.Repeat("0a", 32)+".json")
+	newPath := filepath.Join(dir, strings.Repeat("1b", 32)+".json")
+	otherPath := filepath.Join(dir, "README")
+	foreignPath := filepath.Join(dir, "notes.json")
+	for _, path := range []string{oldPath, newPath, otherPath, foreignPath} {
+		if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldTime := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(oldPath, oldTime, oldTime)
+	os.Chtimes(foreignPath, oldTime, oldTime)
+
+	removed, err := PruneCache(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PruneCache() unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("PruneCache() removed %d entries, want 1", removed)
+	}
+	if _, err := os.Stat(newPath); err != nil {
+		t.Errorf("PruneCache() removed recent entry")
+	}
+
+	removed, err = PruneCache(dir, 0)
+	if err != nil {
+		t.Fatalf("PruneCache() unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("PruneCache(0) removed %d entries, want 1", removed)
+	}
+	for _, path := range []string{otherPath, foreignPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("PruneCache() removed %s, which is not a cache entry", filepath.Base(path))
+		}
+	}
+}
+
+// TestPruneCache_MissingDir tests that pruning a missing directory is not an error.
+func TestPruneCache_MissingDir(t *testing.T) {
+	removed, err := PruneCache(filepath.Join(t.TempDir(), "missing"), 0)
+	if err != nil || removed != 0 {
+		t.Errorf("PruneCache() = %d, %v, want 0, nil", removed, err)
+	}
+}
+
+// rxRecordingSource is a recordingSource which also implements RxReportSource.
+type rxRecordingSource struct {
+	recordingSource
+	rxRequests int
+}
+
+func (r *rxRecordingSource) FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	r.rxRequests++
+	return []ReceptionReport{{TimeStr: startTime.UTC().Format(time.DateTime), RxSign: target.Callsign, TxSign: "W5XYZ"}}, nil
+}
+
+// TestCachingSource_RxReports tests that receiver queries are cached separately from transmitter queries.
+func TestCachingSource_RxReports(t *testing.T) {
+	upstream := &rxRecordingSource{}
+	now := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	source := &CachingSource{Upstream: upstream, Dir: t.TempDir(), Now: func() time.Time { return now }}
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+
+	if _, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour); err != nil {
+		t.Fatalf("FetchReports() unexpected error: %v", err)
+	}
+	for range 2 {
+		result, err := source.FetchRxReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour)
+		if err != nil {
+			t.Fatalf("FetchRxReports() unexpected error: %v", err)
+		}
+		if len(result) != 1 || result[0].RxSign != "W5XYZ" {
+			t.Errorf("FetchRxReports() = %+v", result)
+		}
+	}
+	if upstream.rxRequests != 1 {
+		t.Errorf("FetchRxReports() made %d upstream requests, want 1", upstream.rxRequests)
+	}
+
+	_, err := (&CachingSource{Upstream: &recordingSource{}, Dir: t.TempDir()}).FetchRxReports(ExactCallsign("W5XYZ"), 14, tStart, time.Hour)
+	if err == nil {
+		t.Errorf("FetchRxReports() expected error for upstream without receiver support, got nil")
+	}
+}
@@ -0,0 +1,133 @@
+// This file contains the normalisation and matching of callsigns, so that the
+// target is found under all the forms in which it can appear in the spots:
+// compound callsigns with a prefix or suffix (type 2 messages, e.g. PA/K1ABC
+// or K1ABC/P), callsigns in angle brackets recovered from a hash (type 3
+// messages, e.g. <K1ABC>) and any aliases the station also uses.
+
+package wspr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NormaliseCallsign converts a callsign to the form used for comparisons:
+// upper case, without surrounding white space or the angle brackets of a
+// hashed callsign.
+func NormaliseCallsign(callsign string) string {
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if len(callsign) > 2 && strings.HasPrefix(callsign, "<") && strings.HasSuffix(callsign, ">") {
+		callsign = callsign[1 : len(callsign)-1]
+	}
+	return callsign
+}
+
+// BaseCallsign returns the normalised callsign without any prefix or suffix,
+// taking the longest part between slashes as the base (e.g. K1ABC for
+// PA/K1ABC/P).
+func BaseCallsign(callsign string) string {
+	base := ""
+	for _, part := range strings.Split(NormaliseCallsign(callsign), "/") {
+		if len(part) > len(base) {
+			base = part
+		}
+	}
+	return base
+}
+
+// CallsignMatcher decides which callsigns in the spots belong to a station.
+// Callsigns are compared after NormaliseCallsign, so hashed forms always
+// match. The zero value matches nothing.
+type CallsignMatcher struct {
+	Callsign string
+	// Other callsigns used by the same station.
+	Aliases []string
+	// Also match the callsigns with any prefix, suffix or both (e.g. K1ABC
+	// matches PA/K1ABC, K1ABC/P and PA/K1ABC/P). Prefixes and suffixes of
+	// Callsign and Aliases themselves are ignored.
+	Compound bool
+}
+
+// ExactCallsign returns a matcher for callsign alone.
+func ExactCallsign(callsign string) CallsignMatcher {
+	return CallsignMatcher{Callsign: callsign}
+}
+
+// Return the normalised callsigns matched by m, without duplicates.
+func (m CallsignMatcher) callsigns() []string {
+	var callsigns []string
+	for _, callsign := range append([]string{m.Callsign}, m.Aliases...) {
+		if m.Compound {
+			callsign = BaseCallsign(callsign)
+		} else {
+			callsign = NormaliseCallsign(callsign)
+		}
+		if callsign != "" && !slices.Contains(callsigns, callsign) {
+			callsigns = append(callsigns, callsign)
+		}
+	}
+	return callsigns
+}
+
+// Match reports whether sign, a callsign from a spot, belongs to the station.
+func (m CallsignMatcher) Match(sign string) bool {
+	sign = NormaliseCallsign(sign)
+	for _, callsign := range m.callsigns() {
+		if sign == callsign {
+			return true
+		}
+		if !m.Compound {
+			continue
+		}
+		// Allow at most one part either side of the callsign.
+		parts := strings.Split(sign, "/")
+		if len(parts) > 3 || slices.Contains(parts, "") {
+			continue
+		}
+		for i, part := range parts {
+			if part == callsign && i <= 1 && len(parts)-i <= 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns a description of m which identifies what it matches, e.g.
+// "K1ABC" or "K1ABC|K1XYZ/*" with an alias and compound matching.
+func (m CallsignMatcher) String() string {
+	description := strings.Join(m.callsigns(), "|")
+	if m.Compound {
+		description += "/*"
+	}
+	return description
+}
+
+// Build an SQL condition which selects the spots whose column (e.g.
+// "S.tx_sign") holds a callsign matched by m. It mirrors Match.
+func (m CallsignMatcher) sqlCondition(column string) string {
+	callsigns := m.callsigns()
+	if len(callsigns) == 0 {
+		return "0"
+	}
+	if m.Compound {
+		quoted := make([]string, len(callsigns))
+		for i, callsign := range callsigns {
+			quoted[i] = regexp.QuoteMeta(callsign)
+		}
+		pattern := "^<?([A-Z0-9]+/)?(" + strings.Join(quoted, "|") + ")(/[A-Z0-9]+)?>?$"
+		return fmt.Sprintf("match(%s, %s)", column, sqlString(pattern))
+	}
+	var values []string
+	for _, callsign := range callsigns {
+		values = append(values, sqlString(callsign), sqlString("<"+callsign+">"))
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(values, ", "))
+}
+
+// Quote s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
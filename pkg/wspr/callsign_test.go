@@ -0,0 +1,102 @@
+package wspr
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormaliseCallsign tests case, white space and hash brackets.
+func TestNormaliseCallsign(t *testing.T) {
+	tests := map[string]string{
+		"k1abc":      "K1ABC",
+		" K1ABC ":    "K1ABC",
+		"<K1ABC>":    "K1ABC",
+		"<pa/k1abc>": "PA/K1ABC",
+		"<...>":      "...",
+		"<>":         "<>",
+	}
+
+	for callsign, want := range tests {
+		if got := NormaliseCallsign(callsign); got != want {
+			t.Errorf("NormaliseCallsign(%q) = %q, want %q", callsign, got, want)
+		}
+	}
+}
+
+// TestBaseCallsign tests removing prefixes and suffixes.
+func TestBaseCallsign(t *testing.T) {
+	tests := map[string]string{
+		"K1ABC":        "K1ABC",
+		"PA/K1ABC":     "K1ABC",
+		"K1ABC/P":      "K1ABC",
+		"<pa/k1abc/p>": "K1ABC",
+		"K1ABC/MM":     "K1ABC",
+	}
+
+	for callsign, want := range tests {
+		if got := BaseCallsign(callsign); got != want {
+			t.Errorf("BaseCallsign(%q) = %q, want %q", callsign, got, want)
+		}
+	}
+}
+
+// TestCallsignMatcher tests matching with and without compound callsigns and aliases.
+func TestCallsignMatcher(t *testing.T) {
+	exact := ExactCallsign("K1ABC")
+	compound := CallsignMatcher{Callsign: "K1ABC/P", Aliases: []string{"w1xyz"}, Compound: true}
+	tests := []struct {
+		sign                string
+		wantExact, wantComp bool
+	}{
+		{sign: "K1ABC", wantExact: true, wantComp: true},
+		{sign: "<K1ABC>", wantExact: true, wantComp: true},
+		{sign: "k1abc", wantExact: true, wantComp: true},
+		{sign: "PA/K1ABC", wantExact: false, wantComp: true},
+		{sign: "K1ABC/P", wantExact: false, wantComp: true},
+		{sign: "<PA/K1ABC/P>", wantExact: false, wantComp: true},
+		{sign: "W1XYZ/7", wantExact: false, wantComp: true},
+		{sign: "K1ABCD", wantExact: false, wantComp: false},
+		{sign: "A/B/K1ABC", wantExact: false, wantComp: false},
+		{sign: "K1ABC/", wantExact: false, wantComp: false},
+		{sign: "N0OTH", wantExact: false, wantComp: false},
+	}
+
+	for _, tt := range tests {
+		if got := exact.Match(tt.sign); got != tt.wantExact {
+			t.Errorf("exact Match(%q) = %v, want %v", tt.sign, got, tt.wantExact)
+		}
+		if got := compound.Match(tt.sign); got != tt.wantComp {
+			t.Errorf("compound Match(%q) = %v, want %v", tt.sign, got, tt.wantComp)
+		}
+	}
+	if (CallsignMatcher{}).Match("") {
+		t.Errorf("zero CallsignMatcher matched an empty callsign")
+	}
+}
+
+// TestCallsignMatcher_String tests the description used for cache keys.
+func TestCallsignMatcher_String(t *testing.T) {
+	if got := ExactCallsign("k1abc").String(); got != "K1ABC" {
+		t.Errorf("String() = %q, want K1ABC", got)
+	}
+	matcher := CallsignMatcher{Callsign: "K1ABC/P", Aliases: []string{"W1XYZ", "K1ABC"}, Compound: true}
+	if got := matcher.String(); got != "K1ABC|W1XYZ/*" {
+		t.Errorf("String() = %q, want K1ABC|W1XYZ/*", got)
+	}
+}
+
+// TestCallsignMatcher_SQLCondition tests the SQL conditions used in queries.
+func TestCallsignMatcher_SQLCondition(t *testing.T) {
+	exact := CallsignMatcher{Callsign: "K1ABC", Aliases: []string{"W1XYZ"}}
+	if got, want := exact.sqlCondition("S.tx_sign"), "S.tx_sign IN ('K1ABC', '<K1ABC>', 'W1XYZ', '<W1XYZ>')"; got != want {
+		t.Errorf("sqlCondition() = %q, want %q", got, want)
+	}
+	compound := CallsignMatcher{Callsign: "K1ABC", Compound: true}
+	if got, want := compound.sqlCondition("S.tx_sign"), "match(S.tx_sign, '^<?([A-Z0-9]+/)?(K1ABC)(/[A-Z0-9]+)?>?$')"; got != want {
+		t.Errorf("sqlCondition() = %q, want %q", got, want)
+	}
+	quoted := ExactCallsign("K1'X").sqlCondition("S.tx_sign")
+	if !strings.Contains(quoted, `'K1\'X'`) {
+		t.Errorf("sqlCondition() = %q, quote not escaped", quoted)
+	}
+}
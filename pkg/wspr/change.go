@@ -0,0 +1,102 @@
+// This file contains the before/after comparison, which measures the effect
+// of a change to the target station (such as a new antenna) made at a known
+// time.
+
+package wspr
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// The result of comparing the windows before and after a change to the
+// target. AnalysisParams covers both windows. Shift_dB is the aggregate metric
+// after the change minus that before, so a positive shift is an improvement.
+// The Mann-Whitney test is applied to the pooled aggregate metric samples of
+// the two windows. Samples within a receiver/time group are correlated, so
+// PValue is optimistic; Confidence, which resamples whole groups, is the more
+// reliable guide.
+type ChangeResult struct {
+	AnalysisParams
+	ChangeTime   time.Time
+	Before       *AnalysisResult
+	After        *AnalysisResult
+	Shift_dB     float64
+	Confidence   ConfidenceInterval
+	MannWhitneyU float64
+	PValue       float64
+}
+
+// CompareChange compares the results of analysing the windows before and
+// after a change. The aggregate metrics of both must be valid.
+func CompareChange(params AnalysisParams, changeTime time.Time, before, after *AnalysisResult) (*ChangeResult, error) {
+	if !before.Aggregate.Valid() || !after.Aggregate.Valid() {
+		return nil, fmt.Errorf("too few samples before or after the change to compare")
+	}
+	result := &ChangeResult{
+		AnalysisParams: params,
+		ChangeTime:     changeTime,
+		Before:         before,
+		After:          after,
+		Shift_dB:       after.Aggregate.DbMedian - before.Aggregate.DbMedian,
+		Confidence:     bootstrapShift(before.Groups, after.Groups, params.Aggregation, params.BootstrapIterations, params.BootstrapSeed),
+	}
+	result.MannWhitneyU, result.PValue = mannWhitneyU(pooledSamples(after.Groups), pooledSamples(before.Groups))
+	return result, nil
+}
+
+// RunChangeAnalysis runs the full analysis on the windows of length window
+// either side of changeTime and compares them. The StartTime and Duration of
+// params are ignored.
+func RunChangeAnalysis(source ReportSource, params AnalysisParams, changeTime time.Time, window time.Duration) (*ChangeResult, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("change comparison window must be positive")
+	}
+	params.StartTime = changeTime.Add(-window)
+	params.Duration = 2 * window
+	beforeParams := params
+	beforeParams.Duration = window
+	before, err := RunAnalysis(source, beforeParams)
+	if err != nil {
+		return nil, fmt.Errorf("error analysing the window before the change (%w)", err)
+	}
+	afterParams := params
+	afterParams.StartTime = changeTime
+	afterParams.Duration = window
+	after, err := RunAnalysis(source, afterParams)
+	if err != nil {
+		return nil, fmt.Errorf("error analysing the window after the change (%w)", err)
+	}
+	return CompareChange(params, changeTime, before, after)
+}
+
+// WriteChangeText writes a before/after comparison to w in human-readable
+// form.
+func WriteChangeText(w io.Writer, result *ChangeResult) error {
+	writeDuplicatesText(w, result.Before.DuplicatesRemoved+result.After.DuplicatesRemoved, result.Duplicates)
+	fmt.Fprintf(w, "Offset from median of relative normalised SNR of all other transmitters for %s:\n", result.TargetCallsign)
+	for _, window := range []struct {
+		name   string
+		result *AnalysisResult
+	}{{"Before", result.Before}, {"After", result.After}} {
+		endTime := window.result.StartTime.Add(window.result.Duration)
+		fmt.Fprintf(w, "%-6s %s to %s: %+.1fdBmedian", window.name,
+			window.result.StartTime.UTC().Format(time.RFC3339), endTime.UTC().Format(time.RFC3339),
+			window.result.Aggregate.DbMedian)
+		if result.BootstrapIterations > 0 {
+			fmt.Fprintf(w, ", %v", window.result.Aggregate.Confidence)
+		}
+		fmt.Fprintf(w, " (%d samples, %d groups", window.result.Aggregate.Samples, len(window.result.Groups))
+		if window.result.DuplicatesRemoved > 0 {
+			fmt.Fprintf(w, ", %d duplicate spots removed", window.result.DuplicatesRemoved)
+		}
+		fmt.Fprintf(w, ")\n")
+	}
+	fmt.Fprintf(w, "\nShift after change at %s: %+.1fdB", result.ChangeTime.UTC().Format(time.RFC3339), result.Shift_dB)
+	if result.BootstrapIterations > 0 {
+		fmt.Fprintf(w, ", %v (bootstrap, %d iterations)", result.Confidence, result.BootstrapIterations)
+	}
+	fmt.Fprintf(w, "\nMann-Whitney U = %.1f, p = %.4g\n", result.MannWhitneyU, result.PValue)
+	return nil
+}
@@ -0,0 +1,81 @@
+package wspr
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunChangeAnalysis tests the comparison of the windows either side of a
+// change, using the trend reports (+5dB on the 14th, -3dB on the 16th).
+func TestRunChangeAnalysis(t *testing.T) {
+	params := trendParams
+	params.BootstrapIterations = 100
+	changeTime := time.Date(2024, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	result, err := RunChangeAnalysis(&windowSource{reports: trendReports}, params, changeTime, 36*time.Hour)
+
+	if err != nil {
+		t.Fatalf("RunChangeAnalysis() unexpected error: %v", err)
+	}
+	if result.Before.Aggregate.DbMedian != 5 || result.After.Aggregate.DbMedian != -3 {
+		t.Errorf("RunChangeAnalysis() before %+v after %+v, want +5dB and -3dB", result.Before.Aggregate, result.After.Aggregate)
+	}
+	if result.Shift_dB != -8 {
+		t.Errorf("RunChangeAnalysis() Shift_dB = %v, want -8", result.Shift_dB)
+	}
+	// With one group either side every resample is the same.
+	if !result.Confidence.Valid || result.Confidence.Low != -8 || result.Confidence.High != -8 {
+		t.Errorf("RunChangeAnalysis() Confidence = %+v, want [-8, -8]", result.Confidence)
+	}
+	if result.MannWhitneyU != 0 || result.PValue <= 0 || result.PValue >= 1 {
+		t.Errorf("RunChangeAnalysis() U = %v, p = %v, want U = 0 and 0 < p < 1", result.MannWhitneyU, result.PValue)
+	}
+	if !result.StartTime.Equal(changeTime.Add(-36*time.Hour)) || result.Duration != 72*time.Hour {
+		t.Errorf("RunChangeAnalysis() covers %v for %v, want both windows", result.StartTime, result.Duration)
+	}
+
+	var buf bytes.Buffer
+	WriteChangeText(&buf, result)
+	for _, want := range []string{"Before", "+5.0dBmedian", "-3.0dBmedian", "Shift after change at 2024-12-15T12:00:00Z: -8.0dB", "(bootstrap, 100 iterations)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteChangeText() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// TestRunChangeAnalysis_NoReports tests that an empty window is an error.
+func TestRunChangeAnalysis_NoReports(t *testing.T) {
+	changeTime := time.Date(2024, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	_, err := RunChangeAnalysis(&windowSource{reports: trendReports}, trendParams, changeTime, 6*time.Hour)
+
+	if err == nil || !strings.Contains(err.Error(), "before the change") {
+		t.Errorf("RunChangeAnalysis() error = %v, want error about the window before the change", err)
+	}
+}
+
+// TestRunChangeAnalysis_DuplicatesRemoved tests that the merged spots are
+// counted for each window and shown in the output.
+func TestRunChangeAnalysis_DuplicatesRemoved(t *testing.T) {
+	reports := append(slices.Clone(trendReports), trendReports[1], trendReports[3], trendReports[4])
+	changeTime := time.Date(2024, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	result, err := RunChangeAnalysis(&windowSource{reports: reports}, trendParams, changeTime, 36*time.Hour)
+
+	if err != nil {
+		t.Fatalf("RunChangeAnalysis() unexpected error: %v", err)
+	}
+	if result.Before.DuplicatesRemoved != 1 || result.After.DuplicatesRemoved != 2 {
+		t.Errorf("RunChangeAnalysis() removed %d before and %d after, want 1 and 2", result.Before.DuplicatesRemoved, result.After.DuplicatesRemoved)
+	}
+	var buf bytes.Buffer
+	WriteChangeText(&buf, result)
+	for _, want := range []string{"Removed 3 duplicate spots (policy max-snr)", "1 groups, 2 duplicate spots removed)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteChangeText() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
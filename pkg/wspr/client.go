@@ -0,0 +1,135 @@
+// This file contains the code for interacting with the wspr.live database.
+
+package wspr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Base URL for querying the wspr.live database.
+const baseQueryURL string = "https://db1.wspr.live/?query="
+
+// The columns selected by the queries, matching the JSON tags of
+// ReceptionReport. The frequency is a UInt64, which ClickHouse would quote as
+// a string in JSON, so it is narrowed to fit the Frequency_Hz field.
+const reportColumns = "tx_sign, rx_sign, time, power, distance, azimuth, rx_azimuth, snr, " +
+	"tx_loc, rx_loc, tx_lat, tx_lon, rx_lat, rx_lon, toUInt32(frequency) AS frequency"
+
+// Build a query URL to ask wspr.live for all the reception reports of the
+// target transmitter within the specified time range. Additionally, list all
+// the other transmitters which were received alongside the target transmitter.
+//
+//	target: Callsign(s) of the target transmitter.
+//	band: Integer code of the band (see bandNameToCode in types.go and
+//	      https://wspr.live/ under 'Bands Table').
+//	tStart: Start time for the query.
+//	duration: Query for reception reports up to duration after tStart.
+func BuildQueryUrl(target CallsignMatcher, band int, tStart time.Time, duration time.Duration) string {
+	return buildQueryUrl(baseQueryURL, target, band, tStart, duration)
+}
+
+// Implementation of BuildQueryUrl which allows the base URL to be overridden.
+func buildQueryUrl(baseURL string, target CallsignMatcher, band int, tStart time.Time, duration time.Duration) string {
+	// The outer SQL query just selects the desired columns for the specified
+	// band and time range (this will include all transmitters and receivers).
+	query := fmt.Sprintf("SELECT "+reportColumns+" FROM wspr.rx AS R WHERE "+
+		"band = %d AND "+
+		"time >= '%s' AND "+
+		"time < '%s' AND "+
+		// This nested EXISTS query filters the results with the condition that the
+		// same receiver must also have received the target transmitter at the same
+		// time and on the same band.
+		"EXISTS (SELECT 1 FROM wspr.rx AS S WHERE %s AND S.band = %d AND S.rx_sign = R.rx_sign AND S.time = R.time) "+
+		"ORDER BY time ASC, rx_sign ASC FORMAT JSON",
+		band, tStart.UTC().Format(time.DateTime), tStart.UTC().Add(duration).Format(time.DateTime), target.sqlCondition("S.tx_sign"), band)
+	return baseURL + url.PathEscape(query)
+}
+
+// Build a query URL to ask wspr.live for all the reception reports made by
+// the target receiver within the specified time range. Additionally, list all
+// the other receivers which heard the same transmitters at the same time. This
+// is the receiver-perspective counterpart of BuildQueryUrl.
+//
+//	target: Callsign(s) of the target receiver.
+//	band: Integer code of the band.
+//	tStart: Start time for the query.
+//	duration: Query for reception reports up to duration after tStart.
+func BuildRxQueryUrl(target CallsignMatcher, band int, tStart time.Time, duration time.Duration) string {
+	return buildRxQueryUrl(baseQueryURL, target, band, tStart, duration)
+}
+
+// Implementation of BuildRxQueryUrl which allows the base URL to be overridden.
+func buildRxQueryUrl(baseURL string, target CallsignMatcher, band int, tStart time.Time, duration time.Duration) string {
+	query := fmt.Sprintf("SELECT "+reportColumns+" FROM wspr.rx AS R WHERE "+
+		"band = %d AND "+
+		"time >= '%s' AND "+
+		"time < '%s' AND "+
+		// The same transmitter must also have been heard by the target receiver
+		// at the same time and on the same band.
+		"EXISTS (SELECT 1 FROM wspr.rx AS S WHERE %s AND S.band = %d AND S.tx_sign = R.tx_sign AND S.time = R.time) "+
+		"ORDER BY time ASC, tx_sign ASC FORMAT JSON",
+		band, tStart.UTC().Format(time.DateTime), tStart.UTC().Add(duration).Format(time.DateTime), target.sqlCondition("S.rx_sign"), band)
+	return baseURL + url.PathEscape(query)
+}
+
+// WsprLiveSource is a ReportSource which fetches reception reports from the
+// wspr.live database over HTTP.
+type WsprLiveSource struct {
+	// BaseURL overrides the default wspr.live query URL if non-empty. The SQL
+	// query is appended to it, so it should end with "?query=" or similar.
+	BaseURL string
+}
+
+// FetchReports implements ReportSource by running the query built by
+// BuildQueryUrl against wspr.live.
+func (s *WsprLiveSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = baseQueryURL
+	}
+	reports, err := RunQuery[ReceptionReport](buildQueryUrl(baseURL, target, band, startTime, duration))
+	if err != nil {
+		return nil, fmt.Errorf("error running database query on wspr.live (%w)", err)
+	}
+	return reports, nil
+}
+
+// FetchRxReports implements RxReportSource by running the query built by
+// BuildRxQueryUrl against wspr.live.
+func (s *WsprLiveSource) FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = baseQueryURL
+	}
+	reports, err := RunQuery[ReceptionReport](buildRxQueryUrl(baseURL, target, band, startTime, duration))
+	if err != nil {
+		return nil, fmt.Errorf("error running database query on wspr.live (%w)", err)
+	}
+	return reports, nil
+}
+
+// Perform the actual HTTP GET request to queryURL and unmarshal the JSON
+// response into a slice of ListElementStructs.
+func RunQuery[ListElementStruct any](queryURL string) ([]ListElementStruct, error) {
+	resp, err := http.Get(queryURL)
+	if err != nil {
+		return nil, fmt.Errorf("http.Get() failed (%w)", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body (%w)", err)
+	}
+	var receiverStruct struct {
+		Data []ListElementStruct `json:"data"`
+	}
+	if err := json.Unmarshal(body, &receiverStruct); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON (%w)", err)
+	}
+	return receiverStruct.Data, nil
+}
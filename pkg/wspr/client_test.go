@@ -1,8 +1,9 @@
-package wspranalysis
+package wspr
 
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -35,7 +36,7 @@ func TestBuildQueryUrl(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := BuildQueryUrl(tt.txSign, tt.band, tt.tStart, tt.duration)
+			result := BuildQueryUrl(ExactCallsign(tt.txSign), tt.band, tt.tStart, tt.duration)
 
 			// Check that result starts with base URL
 			if !strings.Contains(result, baseQueryURL) {
@@ -60,13 +61,29 @@ func TestBuildQueryUrl_ContainsTargetCallsign(t *testing.T) {
 	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
 	duration := 24 * time.Hour
 
-	result := BuildQueryUrl(txSign, band, tStart, duration)
+	result := BuildQueryUrl(ExactCallsign(txSign), band, tStart, duration)
 
 	if !strings.Contains(result, txSign) {
 		t.Errorf("BuildQueryUrl() result doesn't contain target callsign: %s", txSign)
 	}
 }
 
+// TestBuildRxQueryUrl tests that the receiver query matches on the target receiver.
+func TestBuildRxQueryUrl(t *testing.T) {
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+
+	result, err := url.PathUnescape(BuildRxQueryUrl(ExactCallsign("W5ABC"), 14, tStart, time.Hour))
+
+	if err != nil {
+		t.Fatalf("BuildRxQueryUrl() returned an invalid URL: %v", err)
+	}
+	for _, want := range []string{"S.rx_sign IN ('W5ABC', '<W5ABC>')", "tx_loc, rx_loc, tx_lat, tx_lon, rx_lat, rx_lon", "S.tx_sign = R.tx_sign", "ORDER BY time ASC, tx_sign ASC"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("BuildRxQueryUrl() = %q, missing %q", result, want)
+		}
+	}
+}
+
 // TestBuildQueryUrl_ContainsTimeRange tests that the query includes the correct time range.
 func TestBuildQueryUrl_TimeRange(t *testing.T) {
 	txSign := "W5XYZ"
@@ -74,7 +91,7 @@ func TestBuildQueryUrl_TimeRange(t *testing.T) {
 	tStart := time.Date(2024, 12, 14, 10, 30, 0, 0, time.UTC)
 	duration := 2 * time.Hour
 
-	result := BuildQueryUrl(txSign, band, tStart, duration)
+	result := BuildQueryUrl(ExactCallsign(txSign), band, tStart, duration)
 
 	// The URL is encoded, so the actual time strings will be percent-encoded.
 	// Just check for the presence of the time values in some form
@@ -235,3 +252,68 @@ func TestRunQuery_MissingFields(t *testing.T) {
 		t.Errorf("RunQuery() returned %d results, want 1", len(result))
 	}
 }
+
+// TestWsprLiveSource_FetchReports tests that WsprLiveSource queries the configured URL.
+func TestWsprLiveSource_FetchReports(t *testing.T) {
+	mockData := `{
+		"data": [
+			{
+				"time": "2024-12-14 15:30:00",
+				"rx_sign": "W5ABC",
+				"tx_sign": "W5XYZ",
+				"power": 10,
+				"snr": -15,
+				"distance": 250,
+				"rx_azimuth": 45,
+				"tx_loc": "EM10",
+				"rx_loc": "EM12",
+				"tx_lat": 30.5,
+				"tx_lon": -97,
+				"rx_lat": 32.5,
+				"rx_lon": -97,
+				"frequency": 14097100
+			}
+		]
+	}`
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(mockData))
+	}))
+	defer server.Close()
+
+	source := &WsprLiveSource{BaseURL: server.URL + "/?query="}
+	result, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC), 24*time.Hour)
+
+	if err != nil {
+		t.Fatalf("FetchReports() unexpected error: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("FetchReports() returned %d results, want 1", len(result))
+	}
+	if result[0].TxLoc != "EM10" || result[0].RxLat_deg != 32.5 || result[0].Frequency_Hz != 14097100 {
+		t.Errorf("FetchReports() geographic fields = %+v", result[0])
+	}
+	if !strings.Contains(gotQuery, "S.tx_sign IN ('W5XYZ', '<W5XYZ>')") {
+		t.Errorf("FetchReports() sent query without target callsign: %s", gotQuery)
+	}
+}
+
+// TestWsprLiveSource_FetchReports_InvalidJSON tests that WsprLiveSource reports decoding errors.
+func TestWsprLiveSource_FetchReports_InvalidJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{invalid json}`))
+	}))
+	defer server.Close()
+
+	source := &WsprLiveSource{BaseURL: server.URL + "/?query="}
+	_, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC), time.Hour)
+
+	if err == nil {
+		t.Errorf("FetchReports() expected error for invalid JSON, got nil")
+	}
+}
@@ -0,0 +1,155 @@
+// This file contains the head-to-head comparison of two transmitters, based on
+// the time slots in which the same receiver heard both of them.
+
+package wspr
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// A time slot in which a receiver heard both of the transmitters being
+// compared.
+type PairedReport struct {
+	RxSign  string
+	Time    time.Time
+	ReportA ReceptionReport
+	ReportB ReceptionReport
+	// Normalised SNR of transmitter A minus that of transmitter B.
+	Difference_dB int8
+}
+
+// Summary of the paired differences seen by a single receiver.
+type ReceiverDifference struct {
+	RxSign              string
+	Samples             int
+	MedianDifference_dB float64
+}
+
+// The result of comparing transmitter A (AnalysisParams.TargetCallsign) with
+// transmitter B (OtherCallsign). Each receiver contributes the median of its
+// paired differences, so that a receiver which heard both transmitters many
+// times does not dominate. MedianDifference_dB is the median across receivers
+// and is only meaningful if Receivers is non-empty.
+type ComparisonResult struct {
+	AnalysisParams
+	OtherCallsign       string
+	Pairs               []PairedReport
+	Receivers           []ReceiverDifference
+	MedianDifference_dB float64
+	Confidence          ConfidenceInterval
+	// Number of duplicate spots merged (see AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+}
+
+// Return the matcher for the callsigns of B in a comparison. B's compound
+// callsigns are matched in the same way as the target's.
+func otherMatcher(params AnalysisParams, otherCallsign string) CallsignMatcher {
+	return CallsignMatcher{Callsign: otherCallsign, Compound: params.CompoundCallsigns}
+}
+
+// CompareReports pairs up the reports of the target (A) and otherCallsign (B)
+// in each report group and calculates the differences between their
+// normalised SNRs. rxReports would normally come from ProcessRawRxReports;
+// groups in which B was not heard are ignored. If B has several reports in a
+// group the strongest is used; RunComparison merges them beforehand according
+// to params.Duplicates.
+func CompareReports(params AnalysisParams, rxReports []ReceptionReportGroup, otherCallsign string) *ComparisonResult {
+	result := &ComparisonResult{AnalysisParams: params, OtherCallsign: otherCallsign}
+	differencesByReceiver := make(map[string][]int8)
+	other := otherMatcher(params, otherCallsign)
+	for _, reportGroup := range rxReports {
+		otherIndex := slices.IndexFunc(reportGroup.Reports, func(report ReceptionReport) bool {
+			return other.Match(report.TxSign)
+		})
+		if otherIndex == -1 {
+			continue
+		}
+		pair := PairedReport{
+			RxSign:  reportGroup.RxSign,
+			Time:    reportGroup.Time,
+			ReportA: reportGroup.Reports[reportGroup.TargetIndex],
+			ReportB: reportGroup.Reports[otherIndex],
+		}
+		pair.Difference_dB = pair.ReportA.SnrNorm_dB(params.NormTxPwr_dBm) - pair.ReportB.SnrNorm_dB(params.NormTxPwr_dBm)
+		result.Pairs = append(result.Pairs, pair)
+		differencesByReceiver[pair.RxSign] = append(differencesByReceiver[pair.RxSign], pair.Difference_dB)
+	}
+	receiverMedians := make([]float64, 0, len(differencesByReceiver))
+	for rxSign, differences := range differencesByReceiver {
+		receiverMedian, _ := median(differences, false)
+		result.Receivers = append(result.Receivers, ReceiverDifference{
+			RxSign:              rxSign,
+			Samples:             len(differences),
+			MedianDifference_dB: receiverMedian,
+		})
+		receiverMedians = append(receiverMedians, receiverMedian)
+	}
+	slices.SortFunc(result.Receivers, func(a, b ReceiverDifference) int {
+		return cmp.Compare(a.RxSign, b.RxSign)
+	})
+	if len(receiverMedians) > 0 {
+		result.MedianDifference_dB, _ = median(receiverMedians, false)
+		result.Confidence = medianConfidenceInterval(receiverMedians, ConfidenceLevel)
+	}
+	return result
+}
+
+// RunComparison fetches the reports for the target (A) from source and
+// compares it with otherCallsign (B). See CompareReports.
+func RunComparison(source ReportSource, params AnalysisParams, otherCallsign string) (*ComparisonResult, error) {
+	rawRxReports, err := source.FetchReports(params.Target(), params.Band, params.StartTime, params.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reception reports (%w)", err)
+	}
+	// B's reports are merged like A's, so that the result does not depend on
+	// which of B's callsigns came first.
+	rxReports, duplicatesRemoved, err := groupRxReports(rawRxReports, params.Target(), params.NormTxPwr_dBm, params.Duplicates, otherMatcher(params, otherCallsign))
+	if err != nil {
+		return nil, err
+	}
+	if params.TargetLocator != "" {
+		rxReports = restrictToTargetLocator(rxReports, params.TargetLocator)
+		duplicatesRemoved = duplicatesRemovedFrom(rawRxReports, rxReports)
+	}
+	if params.Exclusions != nil {
+		rxReports, _ = params.Exclusions.Apply(rxReports)
+	}
+	result := CompareReports(params, rxReports, otherCallsign)
+	if len(result.Pairs) == 0 {
+		return nil, fmt.Errorf("no receiver heard both %s and %s on band %d in the specified time range",
+			params.TargetCallsign, otherCallsign, params.Band)
+	}
+	result.DuplicatesRemoved = duplicatesRemoved
+	return result, nil
+}
+
+// WriteComparisonText writes a comparison result to w in human-readable form.
+// If verbose is set, every paired report is listed.
+func WriteComparisonText(w io.Writer, result *ComparisonResult, verbose bool) error {
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	if verbose {
+		for _, pair := range result.Pairs {
+			fmt.Fprintf(w, "Received by %s at %s: %s %+ddB, %s %+ddB (normalised), difference %+ddB\n",
+				pair.RxSign, pair.Time.UTC().Format(time.RFC3339),
+				pair.ReportA.TxSign, pair.ReportA.SnrNorm_dB(result.NormTxPwr_dBm),
+				pair.ReportB.TxSign, pair.ReportB.SnrNorm_dB(result.NormTxPwr_dBm), pair.Difference_dB)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, receiver := range result.Receivers {
+		fmt.Fprintf(w, "Received by %s: median difference %+.1fdB (%d time slots)\n",
+			receiver.RxSign, receiver.MedianDifference_dB, receiver.Samples)
+	}
+	fmt.Fprintf(w, "\nMedian normalised SNR difference %s - %s: ", result.TargetCallsign, result.OtherCallsign)
+	if len(result.Receivers) > 0 {
+		fmt.Fprintf(w, "%+.1fdB, %v (%d receivers, %d time slots)\n",
+			result.MedianDifference_dB, result.Confidence, len(result.Receivers), len(result.Pairs))
+	} else {
+		fmt.Fprintf(w, "no common receivers\n")
+	}
+	return nil
+}
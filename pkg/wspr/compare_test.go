@@ -0,0 +1,135 @@
+package wspr
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Build a report group in which receiver rxSign heard W5XYZ and N0OTH with the
+// given SNRs (both at 10dBm) at the given minute past 15:00.
+func pairedGroup(rxSign string, minute int, snrA, snrB int8) ReceptionReportGroup {
+	return ReceptionReportGroup{
+		RxSign:      rxSign,
+		Time:        time.Date(2024, 12, 14, 15, minute, 0, 0, time.UTC),
+		TargetIndex: 0,
+		Reports: []ReceptionReport{
+			{RxSign: rxSign, TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: snrA},
+			{RxSign: rxSign, TxSign: "G3ABC", Power_dBm: 30, Snr_dB: -20},
+			{RxSign: rxSign, TxSign: "N0OTH", Power_dBm: 10, Snr_dB: snrB},
+		},
+	}
+}
+
+// TestCompareReports tests the paired differences and per-receiver medians.
+func TestCompareReports(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		pairedGroup("W5ABC", 0, -10, -14),
+		pairedGroup("W5ABC", 2, -10, -12),
+		pairedGroup("W5ABC", 4, -10, -10),
+		pairedGroup("W5DEF", 0, -20, -19),
+		{
+			// N0OTH not heard, so this group is ignored.
+			RxSign:      "K1AAA",
+			TargetIndex: 0,
+			Reports:     []ReceptionReport{{TxSign: "W5XYZ"}, {TxSign: "G3ABC"}},
+		},
+	}
+
+	result := CompareReports(AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43}, rxReports, "N0OTH")
+
+	if len(result.Pairs) != 4 {
+		t.Fatalf("CompareReports() returned %d pairs, want 4", len(result.Pairs))
+	}
+	if result.Pairs[0].Difference_dB != 4 {
+		t.Errorf("CompareReports() first difference = %d, want 4", result.Pairs[0].Difference_dB)
+	}
+	if len(result.Receivers) != 2 {
+		t.Fatalf("CompareReports() returned %d receivers, want 2", len(result.Receivers))
+	}
+	if r := result.Receivers[0]; r.RxSign != "W5ABC" || r.Samples != 3 || r.MedianDifference_dB != 2 {
+		t.Errorf("CompareReports() first receiver = %+v", r)
+	}
+	if r := result.Receivers[1]; r.RxSign != "W5DEF" || r.Samples != 1 || r.MedianDifference_dB != -1 {
+		t.Errorf("CompareReports() second receiver = %+v", r)
+	}
+	// Median of the receiver medians +2 and -1.
+	if result.MedianDifference_dB != 0.5 {
+		t.Errorf("CompareReports() MedianDifference_dB = %v, want 0.5", result.MedianDifference_dB)
+	}
+	if result.Confidence.Valid {
+		t.Errorf("CompareReports() confidence interval should be invalid with 2 receivers")
+	}
+}
+
+// TestRunComparison tests a comparison from a ReportSource end to end.
+func TestRunComparison(t *testing.T) {
+	var reports []ReceptionReport
+	for i, rxSign := range []string{"K1AAA", "K1BBB", "K1CCC", "K1DDD", "K1EEE", "K1FFF"} {
+		reports = append(reports,
+			ReceptionReport{TimeStr: "2024-12-14 15:30:00", RxSign: rxSign, TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: int8(-10 - i)},
+			ReceptionReport{TimeStr: "2024-12-14 15:30:00", RxSign: rxSign, TxSign: "N0OTH", Power_dBm: 20, Snr_dB: int8(-13 - i)},
+		)
+	}
+	params := AnalysisParams{TargetCallsign: "W5XYZ", Band: 14, NormTxPwr_dBm: 43}
+
+	result, err := RunComparison(&fakeSource{reports: reports}, params, "N0OTH")
+
+	if err != nil {
+		t.Fatalf("RunComparison() unexpected error: %v", err)
+	}
+	// A is 3dB stronger and at 10dB less power, so 13dB better when normalised.
+	if result.MedianDifference_dB != 13 || !result.Confidence.Valid || result.Confidence.Low != 13 || result.Confidence.High != 13 {
+		t.Errorf("RunComparison() = %v, %+v, want 13 with CI [13, 13]", result.MedianDifference_dB, result.Confidence)
+	}
+
+	var buf bytes.Buffer
+	WriteComparisonText(&buf, result, false)
+	if !strings.Contains(buf.String(), "W5XYZ - N0OTH: +13.0dB, 97% CI [+13.0, +13.0]dB (6 receivers, 6 time slots)") {
+		t.Errorf("WriteComparisonText() output:\n%s", buf.String())
+	}
+}
+
+// TestRunComparison_OtherCallsignForms tests that B's reports under several
+// callsigns in one slot are merged according to the duplicate policy.
+func TestRunComparison_OtherCallsignForms(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "K1AAA", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "K1AAA", TxSign: "N0OTH/P", Power_dBm: 10, Snr_dB: -20},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "K1AAA", TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -14},
+	}
+	for _, tt := range []struct {
+		policy DuplicatePolicy
+		want   int8
+	}{
+		{policy: DuplicatesMaxSnr, want: 4},
+		{policy: DuplicatesFirst, want: 10},
+		{policy: DuplicatesAverage, want: 7},
+	} {
+		params := AnalysisParams{TargetCallsign: "W5XYZ", CompoundCallsigns: true, NormTxPwr_dBm: 43, Duplicates: tt.policy}
+
+		result, err := RunComparison(&fakeSource{reports: slices.Clone(reports)}, params, "N0OTH")
+
+		if err != nil {
+			t.Fatalf("RunComparison() unexpected error: %v", err)
+		}
+		if len(result.Pairs) != 1 || result.Pairs[0].Difference_dB != tt.want || result.DuplicatesRemoved != 1 {
+			t.Errorf("RunComparison() with %v = %+v, want a difference of %ddB and 1 duplicate removed", tt.policy, result.Pairs, tt.want)
+		}
+	}
+}
+
+// TestRunComparison_NoCommonReceivers tests that an error is returned when B is never heard.
+func TestRunComparison_NoCommonReceivers(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "K1AAA", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10},
+	}
+
+	_, err := RunComparison(&fakeSource{reports: reports}, AnalysisParams{TargetCallsign: "W5XYZ"}, "N0OTH")
+
+	if err == nil {
+		t.Errorf("RunComparison() expected error, got nil")
+	}
+}
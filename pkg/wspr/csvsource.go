@@ -0,0 +1,250 @@
+// This file contains a ReportSource which reads the monthly CSV archives
+// published by wsprnet.org (wsprspots-YYYY-MM.csv) from local disk.
+
+package wspr
+
+import (
+	"cmp"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column indices within a wsprnet.org archive row. The archives have no header
+// and contain the following columns:
+//
+//	spot_id, timestamp, reporter, reporter_grid, snr, frequency, call_sign,
+//	grid, power, drift, distance, azimuth, band, version, code
+const (
+	csvColTimestamp    = 1
+	csvColReporter     = 2
+	csvColReporterGrid = 3
+	csvColSnr          = 4
+	csvColFrequency    = 5
+	csvColCallSign     = 6
+	csvColGrid         = 7
+	csvColPower        = 8
+	csvColDistance     = 10
+	csvColAzimuth      = 11
+	csvColBand         = 12
+	csvMinColumns      = 13
+)
+
+// CSVSource is a ReportSource which reads wsprnet.org CSV archive files. Files
+// with a ".gz" suffix are decompressed on the fly.
+type CSVSource struct {
+	Paths []string
+}
+
+// FetchReports implements ReportSource. Every file in s.Paths is scanned for
+// spots on band within the time range, and the results are restricted to
+// receivers which heard the target in the same time slot (mirroring the
+// EXISTS clause in BuildQueryUrl).
+func (s *CSVSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	if len(s.Paths) == 0 {
+		return nil, fmt.Errorf("no CSV input files specified")
+	}
+	var allReports []ReceptionReport
+	for _, path := range s.Paths {
+		reports, err := readCSVFile(path, band, startTime, startTime.Add(duration))
+		if err != nil {
+			return nil, err
+		}
+		allReports = append(allReports, reports...)
+	}
+	return coReceivedReports(allReports, target), nil
+}
+
+// FetchRxReports implements RxReportSource in the same way as FetchReports,
+// but restricts the results to transmitters which the target heard in the
+// same time slot (mirroring the EXISTS clause in BuildRxQueryUrl).
+func (s *CSVSource) FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	if len(s.Paths) == 0 {
+		return nil, fmt.Errorf("no CSV input files specified")
+	}
+	var allReports []ReceptionReport
+	for _, path := range s.Paths {
+		reports, err := readCSVFile(path, band, startTime, startTime.Add(duration))
+		if err != nil {
+			return nil, err
+		}
+		allReports = append(allReports, reports...)
+	}
+	return coHeardReports(allReports, target), nil
+}
+
+// Read all the reports on band within [tStart, tEnd) from a single archive file.
+func readCSVFile(path string, band int, tStart, tEnd time.Time) ([]ReceptionReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file (%w)", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s (%w)", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	reports, err := parseCSVReports(r, band, tStart, tEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s (%w)", path, err)
+	}
+	return reports, nil
+}
+
+// Parse wsprnet.org archive rows from r, keeping only the reports on band
+// within [tStart, tEnd). A header row, if present, is skipped.
+func parseCSVReports(r io.Reader, band int, tStart, tEnd time.Time) ([]ReceptionReport, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+	var reports []ReceptionReport
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < csvMinColumns {
+			return nil, fmt.Errorf("line %d has %d columns, expected at least %d", line, len(record), csvMinColumns)
+		}
+		timestamp, err := strconv.ParseInt(record[csvColTimestamp], 10, 64)
+		if err != nil {
+			if line == 1 {
+				// Assume this is a header row.
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid timestamp %q", line, record[csvColTimestamp])
+		}
+		reportBand, err := strconv.Atoi(record[csvColBand])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid band %q", line, record[csvColBand])
+		}
+		t := time.Unix(timestamp, 0).UTC()
+		if reportBand != band || t.Before(tStart) || !t.Before(tEnd) {
+			continue
+		}
+		report, err := csvRecordToReport(record, t)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Convert the remaining fields of an archive row into a ReceptionReport.
+func csvRecordToReport(record []string, t time.Time) (ReceptionReport, error) {
+	snr, err := strconv.ParseInt(record[csvColSnr], 10, 8)
+	if err != nil {
+		return ReceptionReport{}, fmt.Errorf("invalid SNR %q", record[csvColSnr])
+	}
+	power, err := strconv.ParseInt(record[csvColPower], 10, 8)
+	if err != nil {
+		return ReceptionReport{}, fmt.Errorf("invalid power %q", record[csvColPower])
+	}
+	distance, err := strconv.ParseUint(record[csvColDistance], 10, 16)
+	if err != nil {
+		return ReceptionReport{}, fmt.Errorf("invalid distance %q", record[csvColDistance])
+	}
+	azimuth, err := strconv.ParseUint(record[csvColAzimuth], 10, 16)
+	if err != nil {
+		return ReceptionReport{}, fmt.Errorf("invalid azimuth %q", record[csvColAzimuth])
+	}
+	frequency_MHz, err := strconv.ParseFloat(record[csvColFrequency], 64)
+	if err != nil {
+		return ReceptionReport{}, fmt.Errorf("invalid frequency %q", record[csvColFrequency])
+	}
+	report := ReceptionReport{
+		TimeStr:      t.Format(time.DateTime),
+		RxSign:       strings.ToUpper(record[csvColReporter]),
+		TxSign:       strings.ToUpper(record[csvColCallSign]),
+		Power_dBm:    int8(power),
+		Snr_dB:       int8(snr),
+		Distance_km:  uint16(distance),
+		TxAzimuth:    uint16(azimuth),
+		TxLoc:        record[csvColGrid],
+		RxLoc:        record[csvColReporterGrid],
+		Frequency_Hz: uint32(math.Round(frequency_MHz * 1e6)),
+	}
+	// The archives have no positions or receiver azimuth, so derive them from
+	// the locators. Invalid locators are left for the rules to reject.
+	txLat, txLon, txErr := LocatorToLatLon(report.TxLoc)
+	if txErr == nil {
+		report.TxLat_deg, report.TxLon_deg = txLat, txLon
+	}
+	rxLat, rxLon, rxErr := LocatorToLatLon(report.RxLoc)
+	if rxErr == nil {
+		report.RxLat_deg, report.RxLon_deg = rxLat, rxLon
+	}
+	if txErr == nil && rxErr == nil {
+		report.RxAzimuth = uint16(math.Round(InitialBearing_deg(rxLat, rxLon, txLat, txLon))) % 360
+	}
+	return report, nil
+}
+
+// Restrict reports to those made by a receiver which also heard the target at
+// the same time, and order them by time followed by receiver callsign as
+// required by ProcessRawRxReports.
+func coReceivedReports(reports []ReceptionReport, target CallsignMatcher) []ReceptionReport {
+	type slotKey struct {
+		timeStr string
+		rxSign  string
+	}
+	targetSlots := make(map[slotKey]bool)
+	for _, report := range reports {
+		if target.Match(report.TxSign) {
+			targetSlots[slotKey{report.TimeStr, report.RxSign}] = true
+		}
+	}
+	var coReceived []ReceptionReport
+	for _, report := range reports {
+		if targetSlots[slotKey{report.TimeStr, report.RxSign}] {
+			coReceived = append(coReceived, report)
+		}
+	}
+	slices.SortStableFunc(coReceived, func(a, b ReceptionReport) int {
+		return cmp.Or(cmp.Compare(a.TimeStr, b.TimeStr), cmp.Compare(a.RxSign, b.RxSign))
+	})
+	return coReceived
+}
+
+// Restrict reports to those of a transmitter which the target also heard at
+// the same time, and order them by time followed by transmitter callsign as
+// required by ProcessRawTxReports.
+func coHeardReports(reports []ReceptionReport, target CallsignMatcher) []ReceptionReport {
+	type slotKey struct {
+		timeStr string
+		txSign  string
+	}
+	targetSlots := make(map[slotKey]bool)
+	for _, report := range reports {
+		if target.Match(report.RxSign) {
+			targetSlots[slotKey{report.TimeStr, report.TxSign}] = true
+		}
+	}
+	var coHeard []ReceptionReport
+	for _, report := range reports {
+		if targetSlots[slotKey{report.TimeStr, report.TxSign}] {
+			coHeard = append(coHeard, report)
+		}
+	}
+	slices.SortStableFunc(coHeard, func(a, b ReceptionReport) int {
+		return cmp.Or(cmp.Compare(a.TimeStr, b.TimeStr), cmp.Compare(a.TxSign, b.TxSign))
+	})
+	return coHeard
+}
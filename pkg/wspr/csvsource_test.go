@@ -0,0 +1,174 @@
+package wspr
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sample wsprnet.org archive rows. 1734190200 is 2024-12-14 15:30:00 UTC.
+const sampleCSV = `1,1734190200,W5ABC,EM12,-10,14.097100,W5XYZ,EM10,10,0,200,45,14,2.6.1,0
+2,1734190200,W5ABC,EM12,-15,14.097120,N0OTH,EN10,20,0,210,30,14,2.6.1,0
+3,1734190200,W5DEF,EM13,-12,14.097130,N0OTH,EN10,20,0,500,30,14,2.6.1,0
+4,1734190200,W5ABC,EM12,-8,7.040100,G3ABC,IO91,30,0,7000,50,7,2.6.1,0
+5,1734190320,W5ABC,EM12,-9,14.097100,W5XYZ,EM10,10,0,200,45,14,2.6.1,0
+6,1734276600,W5ABC,EM12,-9,14.097100,W5XYZ,EM10,10,0,200,45,14,2.6.1,0
+`
+
+// TestParseCSVReports tests parsing and band/time filtering of archive rows.
+func TestParseCSVReports(t *testing.T) {
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+	result, err := parseCSVReports(strings.NewReader(sampleCSV), 14, tStart, tStart.Add(24*time.Hour))
+
+	if err != nil {
+		t.Fatalf("parseCSVReports() unexpected error: %v", err)
+	}
+	// Row 4 is on the wrong band and row 6 is outside the time range.
+	if len(result) != 4 {
+		t.Fatalf("parseCSVReports() returned %d reports, want 4", len(result))
+	}
+	first := result[0]
+	if first.TimeStr != "2024-12-14 15:30:00" || first.RxSign != "W5ABC" || first.TxSign != "W5XYZ" ||
+		first.Power_dBm != 10 || first.Snr_dB != -10 || first.Distance_km != 200 || first.TxAzimuth != 45 {
+		t.Errorf("parseCSVReports() first report = %+v", first)
+	}
+	// EM10 is due south of EM12.
+	if first.TxLoc != "EM10" || first.RxLoc != "EM12" || first.Frequency_Hz != 14097100 ||
+		first.TxLat_deg != 30.5 || first.RxLat_deg != 32.5 || first.RxAzimuth != 180 {
+		t.Errorf("parseCSVReports() first report geography = %+v", first)
+	}
+}
+
+// TestParseCSVReports_Header tests that a header row is skipped.
+func TestParseCSVReports_Header(t *testing.T) {
+	data := "spot_id,timestamp,reporter,reporter_grid,snr,frequency,call_sign,grid,power,drift,distance,azimuth,band,version,code\n" + sampleCSV
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+	result, err := parseCSVReports(strings.NewReader(data), 14, tStart, tStart.Add(24*time.Hour))
+
+	if err != nil {
+		t.Fatalf("parseCSVReports() unexpected error: %v", err)
+	}
+	if len(result) != 4 {
+		t.Errorf("parseCSVReports() returned %d reports, want 4", len(result))
+	}
+}
+
+// TestParseCSVReports_Invalid tests that malformed rows are reported as errors.
+func TestParseCSVReports_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "too few columns", data: "1,1734190200,W5ABC\n"},
+		{name: "bad SNR", data: "1,1734190200,W5ABC,EM12,x,14.0971,W5XYZ,EM10,10,0,200,45,14,2.6.1,0\n"},
+		{name: "bad timestamp after first line", data: sampleCSV + "7,x,W5ABC,EM12,-9,14.0971,W5XYZ,EM10,10,0,200,45,14,2.6.1,0\n"},
+	}
+
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCSVReports(strings.NewReader(tt.data), 14, tStart, tStart.Add(24*time.Hour))
+			if err == nil {
+				t.Errorf("parseCSVReports() expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestCoReceivedReports tests that only receivers which heard the target are kept.
+func TestCoReceivedReports(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5ABC", TxSign: "W5XYZ"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ"},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5DEF", TxSign: "N0OTH"},
+	}
+
+	result := coReceivedReports(reports, ExactCallsign("W5XYZ"))
+
+	if len(result) != 3 {
+		t.Fatalf("coReceivedReports() returned %d reports, want 3", len(result))
+	}
+	for i := 1; i < len(result); i++ {
+		if result[i].TimeStr < result[i-1].TimeStr {
+			t.Errorf("coReceivedReports() reports not ordered by time")
+		}
+	}
+	for _, report := range result {
+		if report.RxSign != "W5ABC" {
+			t.Errorf("coReceivedReports() kept report from %s which did not hear the target", report.RxSign)
+		}
+	}
+}
+
+// TestCoHeardReports tests the receiver-perspective counterpart of coReceivedReports.
+func TestCoHeardReports(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "W5XYZ"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ"},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5DEF", TxSign: "W5XYZ"},
+	}
+
+	result := coHeardReports(reports, ExactCallsign("W5ABC"))
+
+	if len(result) != 2 {
+		t.Fatalf("coHeardReports() returned %d reports, want 2", len(result))
+	}
+	for _, report := range result {
+		if report.TxSign != "W5XYZ" || report.TimeStr != "2024-12-14 15:30:00" {
+			t.Errorf("coHeardReports() kept report %+v which the target did not hear", report)
+		}
+	}
+}
+
+// TestCSVSource_FetchReports tests reading plain and gzipped archive files end to end.
+func TestCSVSource_FetchReports(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "wsprspots-2024-12.csv")
+	if err := os.WriteFile(plainPath, []byte(sampleCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gzPath := filepath.Join(dir, "wsprspots-2024-12.csv.gz")
+	f, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(sampleCSV))
+	gz.Close()
+	f.Close()
+
+	tStart := time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{plainPath, gzPath} {
+		source := &CSVSource{Paths: []string{path}}
+		result, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, tStart, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("FetchReports(%s) unexpected error: %v", path, err)
+		}
+		// W5DEF never heard W5XYZ, so only the three W5ABC reports remain.
+		if len(result) != 3 {
+			t.Errorf("FetchReports(%s) returned %d reports, want 3", path, len(result))
+		}
+		groups, err := ProcessRawRxReports(result, ExactCallsign("W5XYZ"), 43)
+		if err != nil {
+			t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
+		}
+		if len(groups) != 2 {
+			t.Errorf("ProcessRawRxReports() returned %d groups, want 2", len(groups))
+		}
+	}
+}
+
+// TestCSVSource_NoPaths tests that a CSVSource without files returns an error.
+func TestCSVSource_NoPaths(t *testing.T) {
+	source := &CSVSource{}
+	_, err := source.FetchReports(ExactCallsign("W5XYZ"), 14, time.Now(), time.Hour)
+	if err == nil {
+		t.Errorf("FetchReports() expected error with no paths, got nil")
+	}
+}
@@ -0,0 +1,32 @@
+// Package wspr assesses the performance of a WSPR transmitter by comparing the
+// SNR of its reception reports with those of other transmitters heard by the
+// same receivers at the same time.
+//
+// The analysis is a pipeline of independent steps, each of which can be used
+// on its own:
+//
+//  1. Fetch raw reception reports from a ReportSource (WsprLiveSource,
+//     CSVSource or CachingSource).
+//  2. Group them by receiver and time slot with ProcessRawRxReports, or
+//     GroupRxReports to choose how duplicate spots are merged.
+//  3. Remove transmitters which are not comparable with the target with
+//     FilterRxReports, or a FilterChain of ComparabilityRules.
+//  4. Score the target against the remaining transmitters with
+//     AnalyseReports.
+//  5. Write the AnalysisResult out with WriteResult.
+//
+// RunAnalysis performs steps 1-4 in one call:
+//
+//	params := wspr.AnalysisParams{
+//		TargetCallsign: "K1ABC",
+//		Band:           14,
+//		StartTime:      time.Now().Add(-24 * time.Hour),
+//		Duration:       24 * time.Hour,
+//		NormTxPwr_dBm:  43,
+//	}
+//	result, err := wspr.RunAnalysis(&wspr.WsprLiveSource{}, params)
+//
+// RunRxAnalysis is the receiver-perspective counterpart, which assesses the
+// receiver params.TargetCallsign against the other receivers which heard the
+// same transmissions. It needs an RxReportSource.
+package wspr
@@ -0,0 +1,154 @@
+// This file contains the handling of duplicate spots: several reports of the
+// same transmitter by the same receiver in one time slot, which happen when a
+// receiver uploads a spot twice or runs several decoders.
+
+package wspr
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DuplicatePolicy selects which of a set of duplicate spots is kept.
+type DuplicatePolicy int
+
+const (
+	// Keep the spot with the highest normalised SNR.
+	DuplicatesMaxSnr DuplicatePolicy = iota
+	// Keep the spot which came first from the data source.
+	DuplicatesFirst
+	// Keep the first spot, with its SNR replaced by the one giving the mean
+	// of all the spots' normalised SNRs (rounded to the nearest dB).
+	DuplicatesAverage
+)
+
+// Map between duplicate policy names (as used on the command line) and their
+// DuplicatePolicy values.
+var duplicatePolicyNames = map[string]DuplicatePolicy{
+	"max-snr": DuplicatesMaxSnr,
+	"first":   DuplicatesFirst,
+	"average": DuplicatesAverage,
+}
+
+// Return all the duplicate policy names (useful for the CLI help text).
+func DuplicatePolicyNames() []string {
+	names := make([]string, 0, len(duplicatePolicyNames))
+	for k := range duplicatePolicyNames {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Convert a duplicate policy name to its DuplicatePolicy. Returns an error if
+// the name is not recognised.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	if policy, ok := duplicatePolicyNames[strings.ToLower(name)]; ok {
+		return policy, nil
+	}
+	return 0, fmt.Errorf("unrecognised duplicate policy: %s", name)
+}
+
+// Return the name of the policy.
+func (p DuplicatePolicy) String() string {
+	for name, policy := range duplicatePolicyNames {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+}
+
+// Merge the reports which have the same key according to policy. Each merged
+// report takes the place of the first of its duplicates, so the order is
+// otherwise preserved. The reports are modified in place. Returns the merged
+// reports and the number of reports removed.
+func mergeDuplicates(reports []ReceptionReport, key func(ReceptionReport) string, policy DuplicatePolicy, normTxPwr_dBm int8) ([]ReceptionReport, int) {
+	merged := reports[:0]
+	indexByKey := make(map[string]int, len(reports))
+	var snrSums, counts []int
+	for _, report := range reports {
+		k, seen := indexByKey[key(report)]
+		if !seen {
+			indexByKey[key(report)] = len(merged)
+			merged = append(merged, report)
+			snrSums = append(snrSums, int(report.SnrNorm_dB(normTxPwr_dBm)))
+			counts = append(counts, 1)
+			continue
+		}
+		snrSums[k] += int(report.SnrNorm_dB(normTxPwr_dBm))
+		counts[k]++
+		if policy == DuplicatesMaxSnr && report.SnrNorm_dB(normTxPwr_dBm) > merged[k].SnrNorm_dB(normTxPwr_dBm) {
+			merged[k] = report
+		}
+	}
+	if policy == DuplicatesAverage {
+		for k := range merged {
+			if counts[k] > 1 {
+				meanSnrNorm_dB := int8(math.Round(float64(snrSums[k]) / float64(counts[k])))
+				merged[k].Snr_dB = meanSnrNorm_dB - normTxPwr_dBm + merged[k].Power_dBm
+			}
+		}
+	}
+	return merged, len(reports) - len(merged)
+}
+
+// Return a function giving the key under which duplicate reports are merged:
+// the normalised callsign from sign, except that every callsign matched by
+// one of stations shares a key since they are all the same station. The
+// earlier stations take precedence.
+func duplicateKey(sign func(ReceptionReport) string, stations ...CallsignMatcher) func(ReceptionReport) string {
+	return func(report ReceptionReport) string {
+		for i, station := range stations {
+			if station.Match(sign(report)) {
+				// Callsigns cannot contain '*', so this cannot clash.
+				return "*" + strconv.Itoa(i)
+			}
+		}
+		return NormaliseCallsign(sign(report))
+	}
+}
+
+// Count the duplicate spots which were merged when grouping rawRxReports into
+// rxReports with GroupRxReports, which leaves every raw report in the slot of
+// its group. Only the groups in rxReports are counted, so this is the number
+// removed from a subset of the groups.
+func duplicatesRemovedFrom(rawRxReports []ReceptionReport, rxReports []ReceptionReportGroup) int {
+	rawCounts := countBySlot(rawRxReports, func(report ReceptionReport) string { return report.RxSign })
+	removed := 0
+	for _, reportGroup := range rxReports {
+		removed += rawCounts[[2]string{reportGroup.Reports[0].TimeStr, reportGroup.RxSign}] - len(reportGroup.Reports)
+	}
+	return removed
+}
+
+// The receiver-perspective counterpart of duplicatesRemovedFrom, for groups
+// made with GroupTxReports.
+func txDuplicatesRemovedFrom(rawTxReports []ReceptionReport, txReports []TransmissionReportGroup) int {
+	rawCounts := countBySlot(rawTxReports, func(report ReceptionReport) string { return report.TxSign })
+	removed := 0
+	for _, reportGroup := range txReports {
+		removed += rawCounts[[2]string{reportGroup.Reports[0].TimeStr, reportGroup.TxSign}] - len(reportGroup.Reports)
+	}
+	return removed
+}
+
+// Count the reports for each time slot and station given by sign.
+func countBySlot(reports []ReceptionReport, sign func(ReceptionReport) string) map[[2]string]int {
+	counts := make(map[[2]string]int)
+	for _, report := range reports {
+		counts[[2]string{report.TimeStr, sign(report)}]++
+	}
+	return counts
+}
+
+// Write a line saying how many duplicate spots were removed, if any.
+func writeDuplicatesText(w io.Writer, removed int, policy DuplicatePolicy) {
+	if removed > 0 {
+		fmt.Fprintf(w, "Removed %d duplicate spots (policy %s)\n", removed, policy)
+	}
+}
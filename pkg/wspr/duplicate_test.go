@@ -0,0 +1,233 @@
+package wspr
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Reports by one receiver in one slot, in which N0OTH was spotted three times
+// and the target twice, once hashed.
+var duplicateTestReports = []ReceptionReport{
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 43, Snr_dB: -10},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 43, Snr_dB: -20},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "n0oth", Power_dBm: 43, Snr_dB: -5},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "<W5XYZ>", Power_dBm: 43, Snr_dB: -17},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 43, Snr_dB: -12},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "K1DEF", Power_dBm: 43, Snr_dB: -8},
+}
+
+// TestParseDuplicatePolicy tests the duplicate policy names.
+func TestParseDuplicatePolicy(t *testing.T) {
+	for name, want := range map[string]DuplicatePolicy{"max-snr": DuplicatesMaxSnr, "FIRST": DuplicatesFirst, "average": DuplicatesAverage} {
+		if policy, err := ParseDuplicatePolicy(name); err != nil || policy != want {
+			t.Errorf("ParseDuplicatePolicy(%q) = %v, %v, want %v", name, policy, err, want)
+		}
+		if got := want.String(); got != strings.ToLower(name) {
+			t.Errorf("%d.String() = %q, want %q", int(want), got, strings.ToLower(name))
+		}
+	}
+	if _, err := ParseDuplicatePolicy("last"); err == nil {
+		t.Errorf("ParseDuplicatePolicy(\"last\") expected error, got nil")
+	}
+}
+
+// TestGroupRxReports_Duplicates tests that each policy merges the duplicate
+// spots of a transmitter, including the target's hashed callsign.
+func TestGroupRxReports_Duplicates(t *testing.T) {
+	tests := []struct {
+		policy        DuplicatePolicy
+		wantOtherSnr  int8
+		wantTargetSnr int8
+	}{
+		{policy: DuplicatesMaxSnr, wantOtherSnr: -5, wantTargetSnr: -17},
+		{policy: DuplicatesFirst, wantOtherSnr: -10, wantTargetSnr: -20},
+		// -27/3 = -9, and -37/2 = -18.5 rounds away from zero.
+		{policy: DuplicatesAverage, wantOtherSnr: -9, wantTargetSnr: -19},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			groups, removed, err := GroupRxReports(slices.Clone(duplicateTestReports), ExactCallsign("W5XYZ"), 43, tt.policy)
+
+			if err != nil {
+				t.Fatalf("GroupRxReports() unexpected error: %v", err)
+			}
+			if removed != 3 {
+				t.Errorf("GroupRxReports() removed %d reports, want 3", removed)
+			}
+			if len(groups) != 1 || len(groups[0].Reports) != 3 {
+				t.Fatalf("GroupRxReports() = %+v, want one group of 3 reports", groups)
+			}
+			for _, report := range groups[0].Reports {
+				switch NormaliseCallsign(report.TxSign) {
+				case "N0OTH":
+					if report.Snr_dB != tt.wantOtherSnr {
+						t.Errorf("N0OTH SNR = %d, want %d", report.Snr_dB, tt.wantOtherSnr)
+					}
+				case "W5XYZ":
+					if report.Snr_dB != tt.wantTargetSnr {
+						t.Errorf("target SNR = %d, want %d", report.Snr_dB, tt.wantTargetSnr)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestGroupRxReports_NormalisedMaxSnr tests that DuplicatesMaxSnr compares
+// the normalised SNRs of spots reporting different powers.
+func TestGroupRxReports_NormalisedMaxSnr(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 43, Snr_dB: -20},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -15},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 30, Snr_dB: -12},
+	}
+
+	groups, _, err := GroupRxReports(reports, ExactCallsign("W5XYZ"), 43, DuplicatesMaxSnr)
+
+	if err != nil {
+		t.Fatalf("GroupRxReports() unexpected error: %v", err)
+	}
+	if other := groups[0].Reports[1-groups[0].TargetIndex]; other.Power_dBm != 20 {
+		t.Errorf("GroupRxReports() kept %+v, want the 20dBm spot", other)
+	}
+}
+
+// TestGroupRxReports_NormalisedAverage tests that DuplicatesAverage averages
+// the normalised SNRs of spots reporting different powers.
+func TestGroupRxReports_NormalisedAverage(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 43, Snr_dB: -20},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -15},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 30, Snr_dB: -13},
+	}
+
+	groups, _, err := GroupRxReports(reports, ExactCallsign("W5XYZ"), 43, DuplicatesAverage)
+
+	if err != nil {
+		t.Fatalf("GroupRxReports() unexpected error: %v", err)
+	}
+	// The normalised SNRs are +8 and 0, so the mean is +4: -19dB at 20dBm.
+	other := groups[0].Reports[1-groups[0].TargetIndex]
+	if other.Power_dBm != 20 || other.Snr_dB != -19 || other.SnrNorm_dB(43) != 4 {
+		t.Errorf("GroupRxReports() kept %+v, want -19dB at 20dBm", other)
+	}
+}
+
+// TestGroupTxReports_Duplicates tests that duplicate spots by a receiver are
+// merged in receiver-perspective groups.
+func TestGroupTxReports_Duplicates(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5XYZ", TxSign: "K1ABC", Power_dBm: 30, Snr_dB: -15},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "N0OTH", TxSign: "K1ABC", Power_dBm: 30, Snr_dB: -10},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5XYZ", TxSign: "K1ABC", Power_dBm: 30, Snr_dB: -13},
+	}
+
+	groups, removed, err := GroupTxReports(reports, ExactCallsign("W5XYZ"), DuplicatesFirst)
+
+	if err != nil {
+		t.Fatalf("GroupTxReports() unexpected error: %v", err)
+	}
+	if removed != 1 || len(groups) != 1 || len(groups[0].Reports) != 2 {
+		t.Fatalf("GroupTxReports() = %+v, %d removed, want one group of 2 reports and 1 removed", groups, removed)
+	}
+	if target := groups[0].Reports[groups[0].TargetIndex]; target.Snr_dB != -15 {
+		t.Errorf("GroupTxReports() target SNR = %d, want the first spot's -15", target.Snr_dB)
+	}
+}
+
+// TestRunAnalysis_DuplicatesRemoved tests that the number of merged spots is
+// recorded and shown in the text output.
+func TestRunAnalysis_DuplicatesRemoved(t *testing.T) {
+	params := testParams
+	params.Duplicates = DuplicatesAverage
+	result, err := RunAnalysis(&fakeSource{reports: slices.Clone(duplicateTestReports)}, params)
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	if result.DuplicatesRemoved != 3 {
+		t.Errorf("RunAnalysis() DuplicatesRemoved = %d, want 3", result.DuplicatesRemoved)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResultText(&buf, result, TextOptions{}); err != nil {
+		t.Fatalf("WriteResultText() unexpected error: %v", err)
+	}
+	if want := "Removed 3 duplicate spots (policy average)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteResultText() output missing %q:\n%s", want, buf.String())
+	}
+}
+
+// TestDuplicatesRemoved_OtherAnalyses tests that the comparison, multi-band
+// and split-by-locator analyses count and show the merged spots.
+func TestDuplicatesRemoved_OtherAnalyses(t *testing.T) {
+	reports := slices.Clone(duplicateTestReports)
+	for i := range reports {
+		if NormaliseCallsign(reports[i].TxSign) == "W5XYZ" {
+			reports[i].TxLoc = "EM10"
+		}
+	}
+	source := fetchedSource{reports: reports}
+	const want = "Removed 3 duplicate spots (policy max-snr)"
+	var buf bytes.Buffer
+
+	comparison, err := RunComparison(source, testParams, "N0OTH")
+	if err != nil {
+		t.Fatalf("RunComparison() unexpected error: %v", err)
+	}
+	WriteComparisonText(&buf, comparison, false)
+	if comparison.DuplicatesRemoved != 3 || !strings.Contains(buf.String(), want) {
+		t.Errorf("RunComparison() removed %d duplicates, output:\n%s", comparison.DuplicatesRemoved, buf.String())
+	}
+
+	multiBand, err := RunMultiBandAnalysis(source, testParams, []int{7, 14})
+	if err != nil {
+		t.Fatalf("RunMultiBandAnalysis() unexpected error: %v", err)
+	}
+	buf.Reset()
+	WriteMultiBand(&buf, multiBand, FormatCSV)
+	if multiBand.DuplicatesRemoved != 6 || !strings.HasSuffix(strings.TrimSpace(buf.String()), ",6") {
+		t.Errorf("RunMultiBandAnalysis() removed %d duplicates, output:\n%s", multiBand.DuplicatesRemoved, buf.String())
+	}
+
+	split, err := RunLocatorSplitAnalysis(source, testParams)
+	if err != nil {
+		t.Fatalf("RunLocatorSplitAnalysis() unexpected error: %v", err)
+	}
+	buf.Reset()
+	WriteLocatorSplit(&buf, split, FormatText)
+	if split.DuplicatesRemoved != 3 || split.Locators[0].duplicatesRemoved() != 3 || !strings.Contains(buf.String(), want) {
+		t.Errorf("RunLocatorSplitAnalysis() removed %d duplicates, output:\n%s", split.DuplicatesRemoved, buf.String())
+	}
+}
+
+// TestRunAnalysis_DuplicatesRemovedByLocator tests that only the duplicates
+// in the groups of the chosen locator are counted.
+func TestRunAnalysis_DuplicatesRemovedByLocator(t *testing.T) {
+	reports := slices.Clone(duplicateTestReports)
+	for _, report := range duplicateTestReports {
+		report.TimeStr = "2024-12-14 15:32:00"
+		reports = append(reports, report)
+	}
+	for i := range reports {
+		if NormaliseCallsign(reports[i].TxSign) == "W5XYZ" {
+			reports[i].TxLoc = "EM10"
+			if i >= len(duplicateTestReports) {
+				reports[i].TxLoc = "EM20"
+			}
+		}
+	}
+	params := testParams
+	params.TargetLocator = "EM20"
+
+	result, err := RunAnalysis(&fakeSource{reports: reports}, params)
+
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || result.DuplicatesRemoved != 3 {
+		t.Errorf("RunAnalysis() gave %d groups and %d duplicates removed, want 1 and 3", len(result.Groups), result.DuplicatesRemoved)
+	}
+}
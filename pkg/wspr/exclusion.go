@@ -0,0 +1,210 @@
+// This file contains the allow/deny lists used to exclude unreliable receivers
+// and transmitters from the analysis.
+
+package wspr
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A pattern matching callsigns, case-insensitively. Patterns wrapped in
+// slashes (e.g. "/^K[0-9]ABC$/") are regular expressions which must match the
+// whole callsign; anything else is a glob in the syntax of path.Match
+// (e.g. "K1*").
+type CallsignPattern struct {
+	text  string
+	regex *regexp.Regexp
+}
+
+// ParseCallsignPattern parses a glob or regular expression pattern.
+func ParseCallsignPattern(text string) (CallsignPattern, error) {
+	text = strings.TrimSpace(text)
+	if len(text) > 2 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/") {
+		regex, err := regexp.Compile("(?i)^(?:" + text[1:len(text)-1] + ")$")
+		if err != nil {
+			return CallsignPattern{}, fmt.Errorf("invalid callsign pattern %q (%w)", text, err)
+		}
+		return CallsignPattern{text: text, regex: regex}, nil
+	}
+	if text == "" {
+		return CallsignPattern{}, fmt.Errorf("empty callsign pattern")
+	}
+	if _, err := path.Match(text, ""); err != nil {
+		return CallsignPattern{}, fmt.Errorf("invalid callsign pattern %q (%w)", text, err)
+	}
+	return CallsignPattern{text: strings.ToUpper(text)}, nil
+}
+
+// Report whether callsign matches the pattern.
+func (p CallsignPattern) Match(callsign string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(callsign)
+	}
+	matched, _ := path.Match(p.text, strings.ToUpper(callsign))
+	return matched
+}
+
+func (p CallsignPattern) String() string {
+	return p.text
+}
+
+// An allow/deny list of callsigns. A callsign is permitted if it matches no
+// Deny pattern and, if there are any Allow patterns, matches one of them.
+type CallsignList struct {
+	Allow []CallsignPattern
+	Deny  []CallsignPattern
+}
+
+// Report whether callsign is permitted by the list.
+func (l CallsignList) Permits(callsign string) bool {
+	matches := func(p CallsignPattern) bool { return p.Match(callsign) }
+	if slices.ContainsFunc(l.Deny, matches) {
+		return false
+	}
+	return len(l.Allow) == 0 || slices.ContainsFunc(l.Allow, matches)
+}
+
+// Report whether the list permits every callsign.
+func (l CallsignList) Empty() bool {
+	return len(l.Allow) == 0 && len(l.Deny) == 0
+}
+
+// Allow/deny lists for receivers, whose report groups are dropped entirely,
+// and for the transmitters compared with the target. The target itself is
+// never excluded.
+type ExclusionLists struct {
+	Receivers    CallsignList
+	Transmitters CallsignList
+}
+
+// Report whether the lists permit every callsign.
+func (l ExclusionLists) Empty() bool {
+	return l.Receivers.Empty() && l.Transmitters.Empty()
+}
+
+// ParseExclusionLists reads exclusion lists in which each line has the form
+// "allow|deny rx|tx pattern". Blank lines and lines starting with "#" are
+// ignored.
+func ParseExclusionLists(r io.Reader) (ExclusionLists, error) {
+	var lists ExclusionLists
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return ExclusionLists{}, fmt.Errorf("line %d: expected \"allow|deny rx|tx pattern\"", lineNumber)
+		}
+		var list *CallsignList
+		switch strings.ToLower(fields[1]) {
+		case "rx":
+			list = &lists.Receivers
+		case "tx":
+			list = &lists.Transmitters
+		default:
+			return ExclusionLists{}, fmt.Errorf("line %d: expected rx or tx, not %q", lineNumber, fields[1])
+		}
+		pattern, err := ParseCallsignPattern(fields[2])
+		if err != nil {
+			return ExclusionLists{}, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		switch strings.ToLower(fields[0]) {
+		case "allow":
+			list.Allow = append(list.Allow, pattern)
+		case "deny":
+			list.Deny = append(list.Deny, pattern)
+		default:
+			return ExclusionLists{}, fmt.Errorf("line %d: expected allow or deny, not %q", lineNumber, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ExclusionLists{}, fmt.Errorf("error reading exclusion lists (%w)", err)
+	}
+	return lists, nil
+}
+
+// LoadExclusionLists reads exclusion lists from a file (see
+// ParseExclusionLists).
+func LoadExclusionLists(filePath string) (ExclusionLists, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ExclusionLists{}, fmt.Errorf("error opening exclusion list file (%w)", err)
+	}
+	defer file.Close()
+	lists, err := ParseExclusionLists(file)
+	if err != nil {
+		return ExclusionLists{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return lists, nil
+}
+
+// A callsign excluded from the analysis, with the number of report groups
+// (for a receiver) or reports (for a transmitter) removed.
+type ExcludedCallsign struct {
+	Callsign string
+	Count    int
+}
+
+// What was removed by ExclusionLists.Apply, sorted by callsign.
+type Exclusions struct {
+	Receivers    []ExcludedCallsign
+	Transmitters []ExcludedCallsign
+}
+
+// Convert counts of excluded callsigns into a sorted slice.
+func sortedExclusions(counts map[string]int) []ExcludedCallsign {
+	excluded := make([]ExcludedCallsign, 0, len(counts))
+	for callsign, count := range counts {
+		excluded = append(excluded, ExcludedCallsign{Callsign: callsign, Count: count})
+	}
+	slices.SortFunc(excluded, func(a, b ExcludedCallsign) int {
+		return cmp.Compare(a.Callsign, b.Callsign)
+	})
+	return excluded
+}
+
+// Apply drops the report groups of receivers which are not permitted and
+// removes transmitters which are not permitted from the remaining groups.
+// rxReports would normally come from ProcessRawRxReports. Groups may be left
+// with only the target; these are dropped later by filtering.
+func (l ExclusionLists) Apply(rxReports []ReceptionReportGroup) ([]ReceptionReportGroup, Exclusions) {
+	excludedReceivers := make(map[string]int)
+	excludedTransmitters := make(map[string]int)
+	keptReports := make([]ReceptionReportGroup, 0, len(rxReports))
+	for _, reportGroup := range rxReports {
+		if !l.Receivers.Permits(reportGroup.RxSign) {
+			excludedReceivers[reportGroup.RxSign]++
+			continue
+		}
+		if l.Transmitters.Empty() {
+			keptReports = append(keptReports, reportGroup)
+			continue
+		}
+		newReportGroup := reportGroup
+		newReportGroup.Reports = make([]ReceptionReport, 0, len(reportGroup.Reports))
+		for i, report := range reportGroup.Reports {
+			if i == reportGroup.TargetIndex {
+				newReportGroup.TargetIndex = len(newReportGroup.Reports)
+			} else if !l.Transmitters.Permits(report.TxSign) {
+				excludedTransmitters[report.TxSign]++
+				continue
+			}
+			newReportGroup.Reports = append(newReportGroup.Reports, report)
+		}
+		keptReports = append(keptReports, newReportGroup)
+	}
+	return keptReports, Exclusions{
+		Receivers:    sortedExclusions(excludedReceivers),
+		Transmitters: sortedExclusions(excludedTransmitters),
+	}
+}
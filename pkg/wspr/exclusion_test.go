@@ -0,0 +1,185 @@
+package wspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mustParsePatterns parses callsign patterns, failing the test on error.
+func mustParsePatterns(t *testing.T, texts ...string) []CallsignPattern {
+	t.Helper()
+	var patterns []CallsignPattern
+	for _, text := range texts {
+		pattern, err := ParseCallsignPattern(text)
+		if err != nil {
+			t.Fatalf("ParseCallsignPattern(%q) unexpected error: %v", text, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// TestCallsignPattern tests glob and regular expression matching.
+func TestCallsignPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		callsign string
+		want     bool
+	}{
+		{pattern: "K1ABC", callsign: "K1ABC", want: true},
+		{pattern: "k1abc", callsign: "K1ABC", want: true},
+		{pattern: "K1*", callsign: "K1XYZ", want: true},
+		{pattern: "K1*", callsign: "W1XYZ", want: false},
+		{pattern: "K?ABC", callsign: "K9ABC", want: true},
+		{pattern: "/K[0-9]ABC/", callsign: "k7abc", want: true},
+		{pattern: "/K[0-9]ABC/", callsign: "K7ABCD", want: false},
+		{pattern: "/.*\\/P/", callsign: "G3ABC/P", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.callsign, func(t *testing.T) {
+			pattern := mustParsePatterns(t, tt.pattern)[0]
+			if got := pattern.Match(tt.callsign); got != tt.want {
+				t.Errorf("CallsignPattern(%q).Match(%q) = %v, want %v", tt.pattern, tt.callsign, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCallsignPattern_Invalid tests that malformed patterns are rejected.
+func TestParseCallsignPattern_Invalid(t *testing.T) {
+	for _, text := range []string{"", "K1[", "/K1(/"} {
+		if _, err := ParseCallsignPattern(text); err == nil {
+			t.Errorf("ParseCallsignPattern(%q) expected error, got nil", text)
+		}
+	}
+}
+
+// TestCallsignList_Permits tests how allow and deny patterns combine.
+func TestCallsignList_Permits(t *testing.T) {
+	list := CallsignList{Allow: mustParsePatterns(t, "K*", "W*"), Deny: mustParsePatterns(t, "K1BAD")}
+
+	for callsign, want := range map[string]bool{"K1ABC": true, "W5XYZ": true, "G3ABC": false, "K1BAD": false} {
+		if got := list.Permits(callsign); got != want {
+			t.Errorf("CallsignList.Permits(%q) = %v, want %v", callsign, got, want)
+		}
+	}
+	if !(CallsignList{}).Permits("G3ABC") {
+		t.Errorf("empty CallsignList does not permit everything")
+	}
+}
+
+// TestParseExclusionLists tests reading rules from a file.
+func TestParseExclusionLists(t *testing.T) {
+	input := "# Unreliable stations\n\ndeny rx K1BAD\nallow rx K*\nDENY TX /N0(OTH|ISY)/\n"
+
+	lists, err := ParseExclusionLists(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("ParseExclusionLists() unexpected error: %v", err)
+	}
+	if len(lists.Receivers.Deny) != 1 || len(lists.Receivers.Allow) != 1 || len(lists.Transmitters.Deny) != 1 || len(lists.Transmitters.Allow) != 0 {
+		t.Fatalf("ParseExclusionLists() = %+v", lists)
+	}
+	if lists.Transmitters.Permits("N0ISY") || !lists.Transmitters.Permits("N0ABC") {
+		t.Errorf("ParseExclusionLists() transmitter list does not match the regexp")
+	}
+
+	for _, invalid := range []string{"deny K1BAD", "block rx K1BAD", "deny both K1BAD", "deny rx K1["} {
+		if _, err := ParseExclusionLists(strings.NewReader(invalid)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ParseExclusionLists(%q) error = %v, want error on line 1", invalid, err)
+		}
+	}
+}
+
+// TestExclusionLists_Apply tests that denied receivers' groups are dropped,
+// denied transmitters are removed and the target is kept.
+func TestExclusionLists_Apply(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "K1BAD",
+			TargetIndex: 0,
+			Reports:     []ReceptionReport{{TxSign: "W5XYZ"}, {TxSign: "N0OTH"}},
+		},
+		{
+			RxSign:      "W5ABC",
+			TargetIndex: 2,
+			Reports:     []ReceptionReport{{TxSign: "N0OTH"}, {TxSign: "G3ABC"}, {TxSign: "W5XYZ"}, {TxSign: "N0ISY"}},
+		},
+	}
+	lists := ExclusionLists{
+		Receivers:    CallsignList{Deny: mustParsePatterns(t, "K1BAD")},
+		Transmitters: CallsignList{Deny: mustParsePatterns(t, "N0*", "W5XYZ")},
+	}
+
+	result, excluded := lists.Apply(rxReports)
+
+	if len(result) != 1 || result[0].RxSign != "W5ABC" {
+		t.Fatalf("ExclusionLists.Apply() = %+v, want only W5ABC", result)
+	}
+	group := result[0]
+	if len(group.Reports) != 2 || group.Reports[group.TargetIndex].TxSign != "W5XYZ" || group.Reports[0].TxSign != "G3ABC" {
+		t.Errorf("ExclusionLists.Apply() group = %+v, want G3ABC and the target", group)
+	}
+	if len(excluded.Receivers) != 1 || excluded.Receivers[0] != (ExcludedCallsign{"K1BAD", 1}) {
+		t.Errorf("ExclusionLists.Apply() excluded receivers = %+v", excluded.Receivers)
+	}
+	if len(excluded.Transmitters) != 2 || excluded.Transmitters[0].Callsign != "N0ISY" || excluded.Transmitters[1].Callsign != "N0OTH" {
+		t.Errorf("ExclusionLists.Apply() excluded transmitters = %+v, want N0ISY and N0OTH", excluded.Transmitters)
+	}
+}
+
+// TestRunAnalysis_Exclusions tests that exclusions are applied and reported.
+func TestRunAnalysis_Exclusions(t *testing.T) {
+	source := &fakeSource{
+		reports: []ReceptionReport{
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "K1BAD", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 200},
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "K1BAD", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -15, Distance_km: 210},
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 200},
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -15, Distance_km: 210},
+		},
+	}
+	params := testParams
+	params.Exclusions = &ExclusionLists{Receivers: CallsignList{Deny: mustParsePatterns(t, "K1BAD")}}
+
+	result, err := RunAnalysis(source, params)
+
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || result.Groups[0].RxSign != "W5ABC" {
+		t.Errorf("RunAnalysis() groups = %+v, want only W5ABC", result.Groups)
+	}
+	if len(result.Excluded.Receivers) != 1 {
+		t.Errorf("RunAnalysis() excluded = %+v, want K1BAD", result.Excluded)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResultText(&buf, result, TextOptions{}); err != nil {
+		t.Fatalf("WriteResultText() unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Reports from K1BAD excluded (1 time slots)") {
+		t.Errorf("WriteResultText() output does not report the exclusion:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := WriteResultJSON(&buf, result); err != nil {
+		t.Fatalf("WriteResultJSON() unexpected error: %v", err)
+	}
+	var doc struct {
+		Excluded struct {
+			Receivers []struct {
+				Callsign string `json:"callsign"`
+				Count    int    `json:"count"`
+			} `json:"receivers"`
+			Transmitters []any `json:"transmitters"`
+		} `json:"excluded"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+	}
+	if len(doc.Excluded.Receivers) != 1 || doc.Excluded.Receivers[0].Callsign != "K1BAD" || doc.Excluded.Transmitters == nil {
+		t.Errorf("WriteResultJSON() excluded = %+v", doc.Excluded)
+	}
+}
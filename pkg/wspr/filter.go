@@ -0,0 +1,146 @@
+// This file contains the rules used to decide which transmitters in a report
+// group are comparable with the target.
+
+package wspr
+
+import (
+	"fmt"
+	"math"
+)
+
+// A rule deciding whether a transmitter is comparable with the target, given
+// the reports of both from the same receiver at the same time.
+type ComparabilityRule interface {
+	Comparable(target, report ReceptionReport) bool
+}
+
+// Accept transmitters at a similar distance from the receiver to the target.
+// The window extends Percent percent of the target's distance, or Window_km,
+// whichever is larger, either side of the target's distance. The upper bound
+// is at least MinUpperBound_km so that very close targets have some
+// comparables.
+type DistanceRule struct {
+	Percent          float64
+	Window_km        float64
+	MinUpperBound_km float64
+}
+
+func (r DistanceRule) Comparable(target, report ReceptionReport) bool {
+	distance_km := float64(target.Distance_km)
+	window_km := max(distance_km*r.Percent/100, r.Window_km)
+	distanceMin_km := math.Floor(distance_km - window_km)
+	distanceMax_km := max(math.Floor(distance_km+window_km), r.MinUpperBound_km)
+	return float64(report.Distance_km) >= distanceMin_km && float64(report.Distance_km) <= distanceMax_km
+}
+
+// Accept transmitters whose azimuth at the receiver is within
+// MaxDifference_deg of the target's, so that they arrive from a similar
+// direction.
+type RxAzimuthRule struct {
+	MaxDifference_deg float64
+}
+
+func (r RxAzimuthRule) Comparable(target, report ReceptionReport) bool {
+	difference_deg := math.Abs(float64(report.RxAzimuth) - float64(target.RxAzimuth))
+	difference_deg = math.Mod(difference_deg, 360)
+	return min(difference_deg, 360-difference_deg) <= r.MaxDifference_deg
+}
+
+// Accept transmitters with a reported power in the range Min_dBm to Max_dBm
+// inclusive. The target itself is always accepted.
+type PowerRule struct {
+	Min_dBm int8
+	Max_dBm int8
+}
+
+func (r PowerRule) Comparable(target, report ReceptionReport) bool {
+	if report.TxSign == target.TxSign {
+		return true
+	}
+	return report.Power_dBm >= r.Min_dBm && report.Power_dBm <= r.Max_dBm
+}
+
+// Accept stations located within MaxSeparation_km of the target station,
+// using the positions from the reports' locators, so that they share a
+// similar path. In a transmitter analysis the transmitters are compared; in a
+// receiver analysis (see FilterChain.ApplyTx) the receivers are. Stations
+// without a known position are rejected unless they are the target.
+type ProximityRule struct {
+	MaxSeparation_km float64
+}
+
+func (r ProximityRule) Comparable(target, report ReceptionReport) bool {
+	if report == target {
+		return true
+	}
+	var targetLat, targetLon, reportLat, reportLon float64
+	var targetOk, reportOk bool
+	if report.RxSign == target.RxSign {
+		targetLat, targetLon, targetOk = target.TxLatLon()
+		reportLat, reportLon, reportOk = report.TxLatLon()
+	} else {
+		targetLat, targetLon, targetOk = target.RxLatLon()
+		reportLat, reportLon, reportOk = report.RxLatLon()
+	}
+	if !targetOk || !reportOk {
+		return false
+	}
+	return GreatCircleDistance_km(targetLat, targetLon, reportLat, reportLon) <= r.MaxSeparation_km
+}
+
+// A chain of comparability rules, all of which a transmitter must pass to be
+// compared with the target. Groups left with fewer than MinComparables
+// comparable transmitters (excluding the target) are dropped.
+type FilterChain struct {
+	Rules          []ComparabilityRule
+	MinComparables int
+}
+
+// Return the filter chain used by FilterRxReports: transmitters within 25% of
+// the target's distance (with an upper bound of at least 50km), and at least
+// one comparable transmitter per group.
+func DefaultFilterChain() FilterChain {
+	return FilterChain{
+		Rules:          []ComparabilityRule{DistanceRule{Percent: 25, MinUpperBound_km: 50}},
+		MinComparables: 1,
+	}
+}
+
+// Report whether report passes all the rules in the chain.
+func (c FilterChain) comparable(target, report ReceptionReport) bool {
+	for _, rule := range c.Rules {
+		if !rule.Comparable(target, report) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply removes transmitters which are not comparable to the target
+// transmitter from each report group. Groups left with too few comparable
+// transmitters are returned separately (unmodified) as the second return
+// value.
+func (c FilterChain) Apply(rxReports []ReceptionReportGroup, target CallsignMatcher) ([]ReceptionReportGroup, []ReceptionReportGroup, error) {
+	var filteredReports, filteredOut []ReceptionReportGroup
+	for _, reportGroup := range rxReports {
+		targetReport := reportGroup.Reports[reportGroup.TargetIndex]
+		// Build a new report group containing only the comparable reports.
+		filteredListForGroup := make([]ReceptionReport, 0, len(reportGroup.Reports))
+		for _, report := range reportGroup.Reports {
+			if c.comparable(targetReport, report) {
+				filteredListForGroup = append(filteredListForGroup, report)
+			}
+		}
+		newReportGroup, err := NewReceptionReportGroup(filteredListForGroup, target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error building filtered report group (%w)", err)
+		}
+		if newReportGroup == nil || len(newReportGroup.Reports)-1 < max(c.MinComparables, 1) {
+			// Skip groups with insufficient comparable transmitters.
+			filteredOut = append(filteredOut, reportGroup)
+		} else {
+			filteredReports = append(filteredReports, *newReportGroup)
+		}
+	}
+	return filteredReports, filteredOut, nil
+}
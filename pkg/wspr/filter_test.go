@@ -0,0 +1,153 @@
+package wspr
+
+import (
+	"testing"
+)
+
+// TestDistanceRule tests the distance window calculation.
+func TestDistanceRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     DistanceRule
+		target   uint16
+		distance uint16
+		want     bool
+	}{
+		{name: "percentage lower bound", rule: DistanceRule{Percent: 25}, target: 100, distance: 75, want: true},
+		{name: "below percentage", rule: DistanceRule{Percent: 25}, target: 100, distance: 74, want: false},
+		{name: "percentage upper bound", rule: DistanceRule{Percent: 25}, target: 100, distance: 125, want: true},
+		{name: "above percentage", rule: DistanceRule{Percent: 25}, target: 100, distance: 126, want: false},
+		{name: "minimum upper bound", rule: DistanceRule{Percent: 25, MinUpperBound_km: 50}, target: 20, distance: 50, want: true},
+		{name: "absolute window wider", rule: DistanceRule{Percent: 25, Window_km: 100}, target: 100, distance: 190, want: true},
+		{name: "percentage wider", rule: DistanceRule{Percent: 25, Window_km: 100}, target: 1000, distance: 1200, want: true},
+		{name: "outside both", rule: DistanceRule{Percent: 25, Window_km: 100}, target: 1000, distance: 1300, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Comparable(ReceptionReport{Distance_km: tt.target}, ReceptionReport{Distance_km: tt.distance})
+			if got != tt.want {
+				t.Errorf("DistanceRule.Comparable(%d, %d) = %v, want %v", tt.target, tt.distance, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRxAzimuthRule tests that azimuth differences wrap around north.
+func TestRxAzimuthRule(t *testing.T) {
+	rule := RxAzimuthRule{MaxDifference_deg: 30}
+	tests := []struct {
+		target, azimuth uint16
+		want            bool
+	}{
+		{target: 90, azimuth: 120, want: true},
+		{target: 90, azimuth: 121, want: false},
+		{target: 350, azimuth: 15, want: true},
+		{target: 10, azimuth: 330, want: false},
+	}
+
+	for _, tt := range tests {
+		got := rule.Comparable(ReceptionReport{RxAzimuth: tt.target}, ReceptionReport{RxAzimuth: tt.azimuth})
+		if got != tt.want {
+			t.Errorf("RxAzimuthRule.Comparable(%d, %d) = %v, want %v", tt.target, tt.azimuth, got, tt.want)
+		}
+	}
+}
+
+// TestProximityRule tests that the stations compared are the ones which vary within the group.
+func TestProximityRule(t *testing.T) {
+	rule := ProximityRule{MaxSeparation_km: 300}
+	// IO91 and IO81 are about 140km apart; IO91 and JO31 about 600km.
+	target := ReceptionReport{RxSign: "W5ABC", TxSign: "G4XYZ", TxLoc: "IO91", RxLoc: "EM12"}
+	tests := []struct {
+		name   string
+		report ReceptionReport
+		want   bool
+	}{
+		{name: "target", report: target, want: true},
+		{name: "nearby transmitter", report: ReceptionReport{RxSign: "W5ABC", TxSign: "G4AAA", TxLoc: "IO81"}, want: true},
+		{name: "distant transmitter", report: ReceptionReport{RxSign: "W5ABC", TxSign: "DL1AAA", TxLoc: "JO31"}, want: false},
+		{name: "reported position", report: ReceptionReport{RxSign: "W5ABC", TxSign: "DL1AAA", TxLoc: "IO91", TxLat_deg: 51.2, TxLon_deg: 6.8}, want: false},
+		{name: "no locator", report: ReceptionReport{RxSign: "W5ABC", TxSign: "G4BBB"}, want: false},
+		{name: "nearby receiver", report: ReceptionReport{RxSign: "W5DEF", TxSign: "G4XYZ", RxLoc: "EM13"}, want: true},
+		{name: "distant receiver", report: ReceptionReport{RxSign: "W6DEF", TxSign: "G4XYZ", RxLoc: "DM04"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Comparable(target, tt.report); got != tt.want {
+				t.Errorf("ProximityRule.Comparable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPowerRule tests the power range, which does not apply to the target.
+func TestPowerRule(t *testing.T) {
+	rule := PowerRule{Min_dBm: 20, Max_dBm: 37}
+	target := ReceptionReport{TxSign: "W5XYZ", Power_dBm: 10}
+
+	if !rule.Comparable(target, target) {
+		t.Errorf("PowerRule.Comparable() rejected the target")
+	}
+	for _, tt := range []struct {
+		power int8
+		want  bool
+	}{{19, false}, {20, true}, {37, true}, {40, false}} {
+		if got := rule.Comparable(target, ReceptionReport{TxSign: "N0OTH", Power_dBm: tt.power}); got != tt.want {
+			t.Errorf("PowerRule.Comparable(%ddBm) = %v, want %v", tt.power, got, tt.want)
+		}
+	}
+}
+
+// TestFilterChain_Apply tests that every rule must pass and that groups with
+// too few comparables are dropped.
+func TestFilterChain_Apply(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{RxSign: "W5ABC", TxSign: "W5XYZ", Distance_km: 1000, RxAzimuth: 90, Power_dBm: 30},
+				{RxSign: "W5ABC", TxSign: "N0OTH", Distance_km: 1100, RxAzimuth: 100, Power_dBm: 30},
+				{RxSign: "W5ABC", TxSign: "G3ABC", Distance_km: 900, RxAzimuth: 270, Power_dBm: 30}, // Wrong direction
+				{RxSign: "W5ABC", TxSign: "K1AAA", Distance_km: 950, RxAzimuth: 80, Power_dBm: 40},  // Too powerful
+			},
+		},
+		{
+			RxSign:      "W5DEF",
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{RxSign: "W5DEF", TxSign: "W5XYZ", Distance_km: 1000, RxAzimuth: 90, Power_dBm: 30},
+				{RxSign: "W5DEF", TxSign: "N0OTH", Distance_km: 1100, RxAzimuth: 100, Power_dBm: 30},
+				{RxSign: "W5DEF", TxSign: "G3ABC", Distance_km: 1050, RxAzimuth: 95, Power_dBm: 30},
+			},
+		},
+	}
+	chain := FilterChain{
+		Rules: []ComparabilityRule{
+			DistanceRule{Percent: 25},
+			RxAzimuthRule{MaxDifference_deg: 45},
+			PowerRule{Min_dBm: 0, Max_dBm: 37},
+		},
+		MinComparables: 2,
+	}
+
+	result, filteredOut, err := chain.Apply(rxReports, ExactCallsign("W5XYZ"))
+
+	if err != nil {
+		t.Fatalf("FilterChain.Apply() unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0].RxSign != "W5DEF" || len(result[0].Reports) != 3 {
+		t.Errorf("FilterChain.Apply() = %+v, want only W5DEF with 3 reports", result)
+	}
+	if len(filteredOut) != 1 || filteredOut[0].RxSign != "W5ABC" || len(filteredOut[0].Reports) != 4 {
+		t.Errorf("FilterChain.Apply() filtered out %+v, want W5ABC unmodified", filteredOut)
+	}
+
+	chain.MinComparables = 1
+	result, _, _ = chain.Apply(rxReports, ExactCallsign("W5XYZ"))
+	if len(result) != 2 || len(result[0].Reports) != 2 {
+		t.Errorf("FilterChain.Apply() with one comparable = %+v, want both groups, W5ABC with 2 reports", result)
+	}
+}
@@ -0,0 +1,113 @@
+// This file contains the geographic calculations: conversion between
+// Maidenhead grid locators and latitude/longitude, and great-circle distances
+// and bearings.
+
+package wspr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Mean radius of the Earth used for great-circle distances.
+const earthRadius_km = 6371.0
+
+// Size in degrees of longitude and latitude of each successive pair of
+// characters in a Maidenhead locator: field, square, subsquare and extended
+// square.
+var locatorPairSizes_deg = [][2]float64{
+	{20, 10},
+	{2, 1},
+	{2.0 / 24, 1.0 / 24},
+	{2.0 / 240, 1.0 / 240},
+}
+
+// Return the number of values each character of the given pair of a locator
+// can take: letters for the field and subsquare, digits for the others.
+func locatorPairRange(pair int) (base byte, count int) {
+	switch pair {
+	case 0:
+		return 'A', 18
+	case 2:
+		return 'A', 24
+	default:
+		return '0', 10
+	}
+}
+
+// LocatorToLatLon converts a Maidenhead locator of 2, 4, 6 or 8 characters
+// (e.g. "IO91wm") to the latitude and longitude in degrees of the centre of
+// the square it describes. Letters may be in either case.
+func LocatorToLatLon(locator string) (lat_deg, lon_deg float64, err error) {
+	upper := strings.ToUpper(locator)
+	if len(upper) == 0 || len(upper)%2 != 0 || len(upper) > 2*len(locatorPairSizes_deg) {
+		return 0, 0, fmt.Errorf("invalid locator %q: must have 2, 4, 6 or 8 characters", locator)
+	}
+	lon_deg, lat_deg = -180, -90
+	var size [2]float64
+	for pair := 0; pair < len(upper)/2; pair++ {
+		base, count := locatorPairRange(pair)
+		size = locatorPairSizes_deg[pair]
+		lonIndex := int(upper[2*pair]) - int(base)
+		latIndex := int(upper[2*pair+1]) - int(base)
+		if lonIndex < 0 || lonIndex >= count || latIndex < 0 || latIndex >= count {
+			return 0, 0, fmt.Errorf("invalid locator %q", locator)
+		}
+		lon_deg += float64(lonIndex) * size[0]
+		lat_deg += float64(latIndex) * size[1]
+	}
+	return lat_deg + size[1]/2, lon_deg + size[0]/2, nil
+}
+
+// LatLonToLocator converts a latitude and longitude in degrees to a Maidenhead
+// locator of length characters (2, 4, 6 or 8). The subsquare is in lower case
+// by convention.
+func LatLonToLocator(lat_deg, lon_deg float64, length int) (string, error) {
+	if length <= 0 || length%2 != 0 || length > 2*len(locatorPairSizes_deg) {
+		return "", fmt.Errorf("invalid locator length %d: must be 2, 4, 6 or 8", length)
+	}
+	if lat_deg < -90 || lat_deg > 90 || lon_deg < -180 || lon_deg > 180 {
+		return "", fmt.Errorf("position %g, %g out of range", lat_deg, lon_deg)
+	}
+	// Keep the poles and the antimeridian inside the last square.
+	lon := math.Min(lon_deg+180, 360-1e-9)
+	lat := math.Min(lat_deg+90, 180-1e-9)
+	locator := make([]byte, 0, length)
+	for pair := 0; pair < length/2; pair++ {
+		base, _ := locatorPairRange(pair)
+		size := locatorPairSizes_deg[pair]
+		lonIndex := math.Floor(lon / size[0])
+		latIndex := math.Floor(lat / size[1])
+		locator = append(locator, base+byte(lonIndex), base+byte(latIndex))
+		lon -= lonIndex * size[0]
+		lat -= latIndex * size[1]
+	}
+	if length >= 6 {
+		locator[4] += 'a' - 'A'
+		locator[5] += 'a' - 'A'
+	}
+	return string(locator), nil
+}
+
+// GreatCircleDistance_km returns the great-circle distance between two points
+// given by latitude and longitude in degrees.
+func GreatCircleDistance_km(lat1_deg, lon1_deg, lat2_deg, lon2_deg float64) float64 {
+	lat1, lat2 := lat1_deg*math.Pi/180, lat2_deg*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (lon2_deg - lon1_deg) * math.Pi / 180
+	// Haversine formula, which is well conditioned for small distances.
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius_km * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InitialBearing_deg returns the initial bearing in degrees clockwise from
+// true north (0-360) of the great-circle path from the first point to the
+// second.
+func InitialBearing_deg(lat1_deg, lon1_deg, lat2_deg, lon2_deg float64) float64 {
+	lat1, lat2 := lat1_deg*math.Pi/180, lat2_deg*math.Pi/180
+	dLon := (lon2_deg - lon1_deg) * math.Pi / 180
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
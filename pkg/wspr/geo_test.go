@@ -0,0 +1,114 @@
+package wspr
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLocatorToLatLon tests decoding locators of each length to the centre of their square.
+func TestLocatorToLatLon(t *testing.T) {
+	tests := []struct {
+		locator  string
+		lat, lon float64
+	}{
+		{locator: "JJ", lat: 5, lon: 10},
+		{locator: "IO91", lat: 51.5, lon: -1},
+		{locator: "io91wm", lat: 51.521, lon: -0.125},
+		{locator: "IO91WM53", lat: 51.5146, lon: -0.1208},
+		{locator: "AA00", lat: -89.5, lon: -179},
+		{locator: "RR99xx", lat: 89.979, lon: 179.958},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locator, func(t *testing.T) {
+			lat, lon, err := LocatorToLatLon(tt.locator)
+			if err != nil {
+				t.Fatalf("LocatorToLatLon(%q) unexpected error: %v", tt.locator, err)
+			}
+			if math.Abs(lat-tt.lat) > 1e-3 || math.Abs(lon-tt.lon) > 1e-3 {
+				t.Errorf("LocatorToLatLon(%q) = %.4f, %.4f, want %.4f, %.4f", tt.locator, lat, lon, tt.lat, tt.lon)
+			}
+		})
+	}
+}
+
+// TestLocatorToLatLon_Invalid tests that malformed locators are rejected.
+func TestLocatorToLatLon_Invalid(t *testing.T) {
+	for _, locator := range []string{"", "I", "IO9", "SA00", "IO9A", "IO91YA", "IO91WM5", "IO91WM53AA"} {
+		if _, _, err := LocatorToLatLon(locator); err == nil {
+			t.Errorf("LocatorToLatLon(%q) expected error, got nil", locator)
+		}
+	}
+}
+
+// TestLatLonToLocator tests encoding positions, including the edges of the map.
+func TestLatLonToLocator(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		length   int
+		want     string
+	}{
+		{lat: 51.5146, lon: -0.1292, length: 8, want: "IO91wm43"},
+		{lat: 51.5146, lon: -0.1292, length: 6, want: "IO91wm"},
+		{lat: 51.5146, lon: -0.1292, length: 4, want: "IO91"},
+		{lat: -33.87, lon: 151.21, length: 6, want: "QF56od"},
+		{lat: 90, lon: 180, length: 4, want: "RR99"},
+		{lat: -90, lon: -180, length: 2, want: "AA"},
+	}
+
+	for _, tt := range tests {
+		got, err := LatLonToLocator(tt.lat, tt.lon, tt.length)
+		if err != nil {
+			t.Fatalf("LatLonToLocator(%g, %g, %d) unexpected error: %v", tt.lat, tt.lon, tt.length, err)
+		}
+		if got != tt.want {
+			t.Errorf("LatLonToLocator(%g, %g, %d) = %q, want %q", tt.lat, tt.lon, tt.length, got, tt.want)
+		}
+	}
+	if _, err := LatLonToLocator(0, 0, 5); err == nil {
+		t.Errorf("LatLonToLocator() expected error for odd length, got nil")
+	}
+	if _, err := LatLonToLocator(91, 0, 4); err == nil {
+		t.Errorf("LatLonToLocator() expected error for latitude out of range, got nil")
+	}
+}
+
+// TestLocatorRoundTrip tests that encoding the centre of a square gives the same locator.
+func TestLocatorRoundTrip(t *testing.T) {
+	for _, locator := range []string{"FN31pr", "QF56od", "AA00aa", "RR99xx", "KP20le"} {
+		lat, lon, err := LocatorToLatLon(locator)
+		if err != nil {
+			t.Fatalf("LocatorToLatLon(%q) unexpected error: %v", locator, err)
+		}
+		if got, _ := LatLonToLocator(lat, lon, len(locator)); got != locator {
+			t.Errorf("LatLonToLocator(LocatorToLatLon(%q)) = %q", locator, got)
+		}
+	}
+}
+
+// TestGreatCircle tests distances and bearings against known values.
+func TestGreatCircle(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		distance, bearing      float64
+	}{
+		{name: "due north", lat1: 0, lon1: 0, lat2: 10, lon2: 0, distance: 1111.95, bearing: 0},
+		{name: "due east on equator", lat1: 0, lon1: 0, lat2: 0, lon2: 90, distance: 10007.54, bearing: 90},
+		{name: "due south", lat1: 10, lon1: 20, lat2: -10, lon2: 20, distance: 2223.9, bearing: 180},
+		{name: "london to new york", lat1: 51.5074, lon1: -0.1278, lat2: 40.7128, lon2: -74.006, distance: 5570.2, bearing: 288.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := GreatCircleDistance_km(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(distance-tt.distance) > 1 {
+				t.Errorf("GreatCircleDistance_km() = %.2f, want %.2f", distance, tt.distance)
+			}
+			bearing := InitialBearing_deg(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(bearing-tt.bearing) > 0.1 {
+				t.Errorf("InitialBearing_deg() = %.2f, want %.2f", bearing, tt.bearing)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+// This file contains the handling of targets which move between locators
+// during the analysis window, such as portable and mobile stations. Reports
+// from different locations are over different paths, so mixing them makes the
+// results hard to interpret.
+
+package wspr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number of characters of the target's locators which are compared. Type 1
+// messages only carry a 4-character locator, so a station sending both type 1
+// and type 3 messages reports two precisions of the same location.
+const targetLocatorLength = 4
+
+// A locator square which the target reported from, and when.
+type LocatorSpan struct {
+	Locator string
+	// Times of the first and last groups in which the target used Locator.
+	First, Last time.Time
+	Groups      int
+}
+
+// Return the locators the target reported from in rxReports, in order of
+// first use. rxReports must be ordered by time, as returned by
+// ProcessRawRxReports. Locators are compared in upper case and only to
+// 4 characters (e.g. EM10 and EM10ab are the same), and groups in which the
+// target has no locator are ignored.
+func TargetLocators(rxReports []ReceptionReportGroup) []LocatorSpan {
+	var spans []LocatorSpan
+	for _, reportGroup := range rxReports {
+		locator := strings.ToUpper(reportGroup.Reports[reportGroup.TargetIndex].TxLoc)
+		if locator == "" {
+			continue
+		}
+		locator = locator[:min(len(locator), targetLocatorLength)]
+		i := slices.IndexFunc(spans, func(span LocatorSpan) bool { return span.Locator == locator })
+		if i == -1 {
+			spans = append(spans, LocatorSpan{Locator: locator, First: reportGroup.Time})
+			i = len(spans) - 1
+		}
+		spans[i].Last = reportGroup.Time
+		spans[i].Groups++
+	}
+	return spans
+}
+
+// Report whether locator lies within the square given by pattern, a locator
+// of the same or lower precision (e.g. "IO91" matches "IO91wm").
+func MatchLocator(locator, pattern string) bool {
+	return pattern != "" && len(locator) >= len(pattern) && strings.EqualFold(locator[:len(pattern)], pattern)
+}
+
+// Keep only the groups in which the target reported from within the square
+// given by locator (see MatchLocator).
+func restrictToTargetLocator(rxReports []ReceptionReportGroup, locator string) []ReceptionReportGroup {
+	var kept []ReceptionReportGroup
+	for _, reportGroup := range rxReports {
+		if MatchLocator(reportGroup.Reports[reportGroup.TargetIndex].TxLoc, locator) {
+			kept = append(kept, reportGroup)
+		}
+	}
+	return kept
+}
+
+// A ReportSource which returns copies of the reports fetched once from
+// another source which fall in the requested time range, so that they can be
+// analysed several times without fetching them again. The target and band
+// are not checked.
+type fetchedSource struct {
+	reports []ReceptionReport
+}
+
+func (s fetchedSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	// TimeStr sorts in time order, so compare the strings rather than parse
+	// every report.
+	from := startTime.UTC().Format(time.DateTime)
+	until := startTime.Add(duration).UTC().Format(time.DateTime)
+	var reports []ReceptionReport
+	for _, report := range s.reports {
+		if report.TimeStr >= from && report.TimeStr < until {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// The result of the analysis restricted to one of the target's locators.
+type LocatorResult struct {
+	LocatorSpan
+	Result *AnalysisResult
+}
+
+// The result of an analysis split by the target's locator.
+// AnalysisParams.TargetLocator is not used.
+type LocatorSplitResult struct {
+	AnalysisParams
+	Locators []LocatorResult
+	// Number of duplicate spots merged in all the groups (see
+	// AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+}
+
+// RunLocatorSplitAnalysis fetches the reports once and runs the full analysis
+// separately for each locator the target reported from (see TargetLocators).
+// Locators whose groups are all filtered out give a LocatorResult with a nil
+// Result.
+func RunLocatorSplitAnalysis(source ReportSource, params AnalysisParams) (*LocatorSplitResult, error) {
+	rawRxReports, err := source.FetchReports(params.Target(), params.Band, params.StartTime, params.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reception reports (%w)", err)
+	}
+	fetched := fetchedSource{reports: rawRxReports}
+	rxReports, duplicatesRemoved, err := GroupRxReports(slices.Clone(fetched.reports), params.Target(), params.NormTxPwr_dBm, params.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	spans := TargetLocators(rxReports)
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("%w with a locator for %s on band %d in the specified time range", ErrNoReports, params.TargetCallsign, params.Band)
+	}
+	result := &LocatorSplitResult{AnalysisParams: params, Locators: make([]LocatorResult, 0, len(spans)), DuplicatesRemoved: duplicatesRemoved}
+	for _, span := range spans {
+		locatorParams := params
+		locatorParams.TargetLocator = span.Locator
+		locatorResult, err := RunAnalysis(fetched, locatorParams)
+		if err != nil && !errors.Is(err, ErrNoReports) {
+			return nil, fmt.Errorf("error analysing locator %s (%w)", span.Locator, err)
+		}
+		result.Locators = append(result.Locators, LocatorResult{LocatorSpan: span, Result: locatorResult})
+	}
+	return result, nil
+}
+
+// WriteLocatorSplit writes a result split by locator to w in the given
+// format.
+func WriteLocatorSplit(w io.Writer, result *LocatorSplitResult, format OutputFormat) error {
+	switch format {
+	case FormatJSON:
+		return writeLocatorSplitJSON(w, result)
+	case FormatCSV:
+		return writeLocatorSplitCSV(w, result, ',')
+	case FormatTSV:
+		return writeLocatorSplitCSV(w, result, '\t')
+	default:
+		return writeLocatorSplitText(w, result)
+	}
+}
+
+// Return the aggregate metric and percentile rank of a locator, which are
+// zero if its groups were all filtered out.
+func (r LocatorResult) summary() (AggregateMetric, PercentileRankMetric) {
+	if r.Result == nil {
+		return AggregateMetric{}, PercentileRankMetric{}
+	}
+	return r.Result.Aggregate, r.Result.PercentileRank
+}
+
+// Return the number of duplicate spots merged in the groups of a locator.
+func (r LocatorResult) duplicatesRemoved() int {
+	if r.Result == nil {
+		return 0
+	}
+	return r.Result.DuplicatesRemoved
+}
+
+// Write a result split by locator as a table.
+func writeLocatorSplitText(w io.Writer, result *LocatorSplitResult) error {
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	fmt.Fprintf(w, "Offset from median of relative normalised SNR of all other transmitters for %s by locator:\n", result.TargetCallsign)
+	fmt.Fprintf(w, "    %-9s %-20s  %-20s %10s %8s %8s %11s\n", "Locator", "First", "Last", "dBmedian", "Samples", "Groups", "Percentile")
+	for _, locator := range result.Locators {
+		aggregate, percentileRank := locator.summary()
+		dbMedian, percentile := "-", "-"
+		if aggregate.Valid() {
+			dbMedian = fmt.Sprintf("%+.1f", aggregate.DbMedian)
+		}
+		if percentileRank.Groups > 0 {
+			percentile = fmt.Sprintf("%.1f%%", percentileRank.Mean)
+		}
+		fmt.Fprintf(w, "    %-9s %-20s  %-20s %10s %8d %8d %11s\n", locator.Locator,
+			locator.First.UTC().Format(time.RFC3339), locator.Last.UTC().Format(time.RFC3339),
+			dbMedian, aggregate.Samples, percentileRank.Groups, percentile)
+	}
+	return nil
+}
+
+// Top level of the JSON document written for an analysis split by locator.
+// The schema is versioned along with the main document (see
+// JSONSchemaVersion).
+type jsonLocatorSplitDocument struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Target        string               `json:"target"`
+	StartTime     string               `json:"start_time"`
+	EndTime       string               `json:"end_time"`
+	NormPower_dBm int8                 `json:"norm_power_dbm"`
+	Duplicates    jsonDuplicates       `json:"duplicates"`
+	Locators      []jsonLocatorSummary `json:"locators"`
+}
+
+// The summary of one locator. DbMedian, Confidence and PercentileRank are
+// null if they could not be calculated.
+type jsonLocatorSummary struct {
+	Locator           string          `json:"locator"`
+	First             string          `json:"first"`
+	Last              string          `json:"last"`
+	DbMedian          *float64        `json:"db_median"`
+	Confidence        *jsonConfidence `json:"confidence"`
+	Samples           int             `json:"samples"`
+	Groups            int             `json:"groups"`
+	PercentileRank    *float64        `json:"percentile_rank"`
+	DuplicatesRemoved int             `json:"duplicates_removed"`
+}
+
+// Write a result split by locator as a JSON document.
+func writeLocatorSplitJSON(w io.Writer, result *LocatorSplitResult) error {
+	doc := jsonLocatorSplitDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Target:        result.TargetCallsign,
+		StartTime:     result.StartTime.UTC().Format(time.RFC3339),
+		EndTime:       result.StartTime.Add(result.Duration).UTC().Format(time.RFC3339),
+		NormPower_dBm: result.NormTxPwr_dBm,
+		Duplicates:    jsonDuplicates{Policy: result.Duplicates.String(), Removed: result.DuplicatesRemoved},
+		Locators:      make([]jsonLocatorSummary, 0, len(result.Locators)),
+	}
+	for _, locator := range result.Locators {
+		aggregate, percentileRank := locator.summary()
+		summary := jsonLocatorSummary{
+			Locator:           locator.Locator,
+			First:             locator.First.UTC().Format(time.RFC3339),
+			Last:              locator.Last.UTC().Format(time.RFC3339),
+			Samples:           aggregate.Samples,
+			Groups:            percentileRank.Groups,
+			DuplicatesRemoved: locator.duplicatesRemoved(),
+		}
+		if aggregate.Valid() {
+			summary.DbMedian = &aggregate.DbMedian
+			if confidence := aggregate.Confidence; confidence.Valid {
+				summary.Confidence = &jsonConfidence{Low: confidence.Low, High: confidence.High, Level: confidence.Level}
+			}
+		}
+		if percentileRank.Groups > 0 {
+			summary.PercentileRank = &percentileRank.Mean
+		}
+		doc.Locators = append(doc.Locators, summary)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON output (%w)", err)
+	}
+	return nil
+}
+
+// Write a result split by locator as CSV with one row per locator. Empty
+// fields mean the value could not be calculated.
+func writeLocatorSplitCSV(w io.Writer, result *LocatorSplitResult, comma rune) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+	csvWriter.Write([]string{"locator", "first", "last", "db_median", "samples", "groups", "percentile_rank", "duplicates_removed"})
+	for _, locator := range result.Locators {
+		aggregate, percentileRank := locator.summary()
+		dbMedian, percentile := "", ""
+		if aggregate.Valid() {
+			dbMedian = strconv.FormatFloat(aggregate.DbMedian, 'f', 1, 64)
+		}
+		if percentileRank.Groups > 0 {
+			percentile = strconv.FormatFloat(percentileRank.Mean, 'f', 1, 64)
+		}
+		csvWriter.Write([]string{locator.Locator, locator.First.UTC().Format(time.RFC3339), locator.Last.UTC().Format(time.RFC3339),
+			dbMedian, strconv.Itoa(aggregate.Samples), strconv.Itoa(percentileRank.Groups), percentile, strconv.Itoa(locator.duplicatesRemoved())})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output (%w)", err)
+	}
+	return nil
+}
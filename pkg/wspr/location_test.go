@@ -0,0 +1,177 @@
+package wspr
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Reports of a target which moves from EM10 to EM20 and back, heard by W5ABC
+// alongside N0OTH.
+func movingTargetReports() []ReceptionReport {
+	var reports []ReceptionReport
+	for i, locator := range []string{"EM10ab", "EM10ab", "EM20", "em10ab"} {
+		timeStr := time.Date(2024, 12, 14, 15, 2*i, 0, 0, time.UTC).Format(time.DateTime)
+		reports = append(reports,
+			ReceptionReport{TimeStr: timeStr, RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -10, Distance_km: 200, TxLoc: "EM11"},
+			ReceptionReport{TimeStr: timeStr, RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 23, Snr_dB: int8(-12 + 5*i), Distance_km: 210, TxLoc: locator},
+		)
+	}
+	return reports
+}
+
+// TestTargetLocators tests detecting the locators the target used.
+func TestTargetLocators(t *testing.T) {
+	rxReports, err := ProcessRawRxReports(movingTargetReports(), ExactCallsign("W5XYZ"), 43)
+	if err != nil {
+		t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
+	}
+
+	spans := TargetLocators(rxReports)
+
+	if len(spans) != 2 {
+		t.Fatalf("TargetLocators() returned %d spans, want 2: %+v", len(spans), spans)
+	}
+	first := spans[0]
+	if first.Locator != "EM10" || first.Groups != 3 || first.First.Minute() != 0 || first.Last.Minute() != 6 {
+		t.Errorf("TargetLocators() first span = %+v", first)
+	}
+	if spans[1].Locator != "EM20" || spans[1].Groups != 1 {
+		t.Errorf("TargetLocators() second span = %+v", spans[1])
+	}
+}
+
+// TestTargetLocators_MixedPrecision tests that 4- and 6-character locators in
+// the same square are one location, so splitting by locator counts every
+// group once.
+func TestTargetLocators_MixedPrecision(t *testing.T) {
+	reports := movingTargetReports()
+	for i := range reports {
+		if reports[i].TxLoc == "EM20" {
+			reports[i].TxLoc = "EM10"
+		}
+	}
+
+	rxReports, err := ProcessRawRxReports(slices.Clone(reports), ExactCallsign("W5XYZ"), 43)
+	if err != nil {
+		t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
+	}
+	if spans := TargetLocators(rxReports); len(spans) != 1 || spans[0].Locator != "EM10" || spans[0].Groups != 4 {
+		t.Errorf("TargetLocators() = %+v, want one EM10 span of 4 groups", spans)
+	}
+
+	result, err := RunLocatorSplitAnalysis(&fakeSource{reports: reports}, testParams)
+	if err != nil {
+		t.Fatalf("RunLocatorSplitAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Locators) != 1 || result.Locators[0].Result == nil || len(result.Locators[0].Result.Groups) != 4 {
+		t.Errorf("RunLocatorSplitAnalysis() = %+v, want one locator with 4 groups", result.Locators)
+	}
+}
+
+// TestMatchLocator tests matching locators against squares of lower precision.
+func TestMatchLocator(t *testing.T) {
+	tests := []struct {
+		locator, pattern string
+		want             bool
+	}{
+		{locator: "IO91wm", pattern: "IO91", want: true},
+		{locator: "IO91wm", pattern: "io91WM", want: true},
+		{locator: "IO91wm", pattern: "IO92", want: false},
+		{locator: "IO91", pattern: "IO91wm", want: false},
+		{locator: "IO91", pattern: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchLocator(tt.locator, tt.pattern); got != tt.want {
+			t.Errorf("MatchLocator(%q, %q) = %v, want %v", tt.locator, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// TestRunAnalysis_TargetLocator tests restricting the analysis to one locator.
+func TestRunAnalysis_TargetLocator(t *testing.T) {
+	params := testParams
+	params.TargetLocator = "EM20"
+
+	result, err := RunAnalysis(&fakeSource{reports: movingTargetReports()}, params)
+
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || len(result.TargetLocators) != 2 {
+		t.Errorf("RunAnalysis() gave %d groups and %d locators, want 1 and 2", len(result.Groups), len(result.TargetLocators))
+	}
+
+	params.TargetLocator = "FN31"
+	if _, err := RunAnalysis(&fakeSource{reports: movingTargetReports()}, params); !errors.Is(err, ErrNoReports) {
+		t.Errorf("RunAnalysis() error = %v, want wrapped ErrNoReports for unused locator", err)
+	}
+}
+
+// TestWriteResultText_MovingTarget tests the warning about a target which moved.
+func TestWriteResultText_MovingTarget(t *testing.T) {
+	result, err := RunAnalysis(&fakeSource{reports: movingTargetReports()}, testParams)
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+
+	WriteResultText(&buf, result, TextOptions{})
+
+	output := buf.String()
+	for _, want := range []string{
+		"W5XYZ reported from 2 locators, which are different paths; the results mix them",
+		"    EM10 from 2024-12-14T15:00:00Z to 2024-12-14T15:06:00Z (3 groups)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("WriteResultText() output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+// TestRunLocatorSplitAnalysis tests analysing each locator separately from one fetch.
+func TestRunLocatorSplitAnalysis(t *testing.T) {
+	source := &fakeSource{reports: movingTargetReports()}
+
+	result, err := RunLocatorSplitAnalysis(source, testParams)
+
+	if err != nil {
+		t.Fatalf("RunLocatorSplitAnalysis() unexpected error: %v", err)
+	}
+	if source.calls != 1 {
+		t.Errorf("RunLocatorSplitAnalysis() called FetchReports %d times, want 1", source.calls)
+	}
+	if len(result.Locators) != 2 {
+		t.Fatalf("RunLocatorSplitAnalysis() returned %d locators, want 2", len(result.Locators))
+	}
+	for i, want := range []int{3, 1} {
+		if result.Locators[i].Result == nil || len(result.Locators[i].Result.Groups) != want {
+			t.Errorf("locator %s result = %+v, want %d groups", result.Locators[i].Locator, result.Locators[i].Result, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLocatorSplit(&buf, result, FormatCSV); err != nil {
+		t.Fatalf("WriteLocatorSplit() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "locator,first,last,db_median,samples,groups,percentile_rank,duplicates_removed" ||
+		!strings.HasPrefix(lines[2], "EM20,2024-12-14T15:04:00Z,2024-12-14T15:04:00Z,") {
+		t.Errorf("WriteLocatorSplit() CSV =\n%s", buf.String())
+	}
+}
+
+// TestRunLocatorSplitAnalysis_NoLocators tests that reports without locators give ErrNoReports.
+func TestRunLocatorSplitAnalysis_NoLocators(t *testing.T) {
+	_, err := RunLocatorSplitAnalysis(&fakeSource{reports: []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ"},
+	}}, testParams)
+
+	if !errors.Is(err, ErrNoReports) {
+		t.Errorf("RunLocatorSplitAnalysis() error = %v, want wrapped ErrNoReports", err)
+	}
+}
@@ -0,0 +1,249 @@
+// This file contains the multi-band analysis, which runs the full analysis on
+// several bands at once and summarises the results.
+
+package wspr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Maximum number of bands fetched at the same time, to avoid overloading the
+// data source.
+const maxConcurrentBands = 4
+
+// The result of the analysis of one band of a multi-band analysis. Result is
+// nil if there were no reports for the target on the band, and has no groups
+// if they were all filtered out.
+type BandResult struct {
+	Band   int
+	Result *AnalysisResult
+}
+
+// The result of a multi-band analysis. AnalysisParams.Band is not used.
+// Combined and CombinedPercentileRank pool the groups of all the bands, and
+// are zero if every group was filtered out.
+type MultiBandResult struct {
+	AnalysisParams
+	Bands                  []BandResult
+	Combined               AggregateMetric
+	CombinedPercentileRank PercentileRankMetric
+	// Number of duplicate spots merged on all the bands (see
+	// AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+}
+
+// RunMultiBandAnalysis runs the full analysis on each of bands, fetching the
+// reports for several bands concurrently. Bands with no reports for the
+// target give a BandResult with a nil Result rather than an error, but it is
+// an error if no band has any reports. If there were reports but every group
+// was filtered out, the result is returned with no combined summary.
+func RunMultiBandAnalysis(source ReportSource, params AnalysisParams, bands []int) (*MultiBandResult, error) {
+	result := &MultiBandResult{AnalysisParams: params, Bands: make([]BandResult, len(bands))}
+	errs := make([]error, len(bands))
+	semaphore := make(chan struct{}, maxConcurrentBands)
+	var wg sync.WaitGroup
+	for i, band := range bands {
+		result.Bands[i].Band = band
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			bandParams := params
+			bandParams.Band = band
+			bandResult, err := RunAnalysis(source, bandParams)
+			if err != nil && !errors.Is(err, ErrNoReports) {
+				errs[i] = fmt.Errorf("error analysing band %s (%w)", BandCodeToName(band), err)
+			}
+			result.Bands[i].Result = bandResult
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	var allGroups []GroupResult
+	anyReports := false
+	for _, bandResult := range result.Bands {
+		if bandResult.Result != nil {
+			anyReports = true
+			allGroups = append(allGroups, bandResult.Result.Groups...)
+			result.DuplicatesRemoved += bandResult.Result.DuplicatesRemoved
+		}
+	}
+	if !anyReports {
+		return nil, fmt.Errorf("%w for %s on any of the bands in the specified time range", ErrNoReports, params.TargetCallsign)
+	}
+	if len(allGroups) == 0 {
+		return result, nil
+	}
+	result.Combined = aggregateMetric(allGroups, params.Aggregation)
+	if result.Combined.Valid() {
+		result.Combined.Confidence = bootstrapAggregate(allGroups, params.Aggregation, params.BootstrapIterations, params.BootstrapSeed)
+	}
+	for _, group := range allGroups {
+		result.CombinedPercentileRank.Mean += group.PercentileRank
+	}
+	result.CombinedPercentileRank.Groups = len(allGroups)
+	result.CombinedPercentileRank.Mean /= float64(len(allGroups))
+	return result, nil
+}
+
+// WriteMultiBand writes a multi-band result to w in the given format.
+func WriteMultiBand(w io.Writer, result *MultiBandResult, format OutputFormat) error {
+	switch format {
+	case FormatJSON:
+		return writeMultiBandJSON(w, result)
+	case FormatCSV:
+		return writeMultiBandCSV(w, result, ',')
+	case FormatTSV:
+		return writeMultiBandCSV(w, result, '\t')
+	default:
+		return writeMultiBandText(w, result)
+	}
+}
+
+// A row of the multi-band table: a band or the combined summary.
+type multiBandRow struct {
+	name              string
+	aggregate         AggregateMetric
+	percentileRank    PercentileRankMetric
+	duplicatesRemoved int
+}
+
+// Return the rows of the multi-band table, with the combined summary last.
+func multiBandRows(result *MultiBandResult) []multiBandRow {
+	rows := make([]multiBandRow, 0, len(result.Bands)+1)
+	for _, bandResult := range result.Bands {
+		row := multiBandRow{name: BandCodeToName(bandResult.Band)}
+		if bandResult.Result != nil {
+			row.aggregate = bandResult.Result.Aggregate
+			row.percentileRank = bandResult.Result.PercentileRank
+			row.duplicatesRemoved = bandResult.Result.DuplicatesRemoved
+		}
+		rows = append(rows, row)
+	}
+	return append(rows, multiBandRow{name: "combined", aggregate: result.Combined, percentileRank: result.CombinedPercentileRank, duplicatesRemoved: result.DuplicatesRemoved})
+}
+
+// Write a multi-band result as a table.
+func writeMultiBandText(w io.Writer, result *MultiBandResult) error {
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	fmt.Fprintf(w, "Offset from median of relative normalised SNR of all other transmitters for %s by band:\n", result.TargetCallsign)
+	fmt.Fprintf(w, "    %-9s %10s %8s %8s %11s\n", "Band", "dBmedian", "Samples", "Groups", "Percentile")
+	for _, row := range multiBandRows(result) {
+		dbMedian, percentile := "-", "-"
+		if row.aggregate.Valid() {
+			dbMedian = fmt.Sprintf("%+.1f", row.aggregate.DbMedian)
+		}
+		if row.percentileRank.Groups > 0 {
+			percentile = fmt.Sprintf("%.1f%%", row.percentileRank.Mean)
+		}
+		fmt.Fprintf(w, "    %-9s %10s %8d %8d %11s\n", row.name, dbMedian, row.aggregate.Samples, row.percentileRank.Groups, percentile)
+	}
+	if result.BootstrapIterations > 0 && result.Combined.Valid() {
+		fmt.Fprintf(w, "\nCombined: %+.1fdBmedian, %v (bootstrap, %d iterations)\n",
+			result.Combined.DbMedian, result.Combined.Confidence, result.BootstrapIterations)
+	}
+	return nil
+}
+
+// Top level of the JSON document written for a multi-band analysis. The
+// schema is versioned along with the main document (see JSONSchemaVersion).
+type jsonMultiBandDocument struct {
+	SchemaVersion int               `json:"schema_version"`
+	Target        string            `json:"target"`
+	StartTime     string            `json:"start_time"`
+	EndTime       string            `json:"end_time"`
+	NormPower_dBm int8              `json:"norm_power_dbm"`
+	Duplicates    jsonDuplicates    `json:"duplicates"`
+	Bands         []jsonBandSummary `json:"bands"`
+	Combined      jsonBandSummary   `json:"combined"`
+	Confidence    *jsonConfidence   `json:"combined_confidence"`
+}
+
+// The summary of one band, or of all the bands combined (in which case Band
+// and BandName are omitted). DbMedian and PercentileRank are null if they
+// could not be calculated.
+type jsonBandSummary struct {
+	Band              *int     `json:"band,omitempty"`
+	BandName          string   `json:"band_name,omitempty"`
+	DbMedian          *float64 `json:"db_median"`
+	Samples           int      `json:"samples"`
+	Groups            int      `json:"groups"`
+	PercentileRank    *float64 `json:"percentile_rank"`
+	DuplicatesRemoved int      `json:"duplicates_removed"`
+}
+
+// Convert a row of the multi-band table to its JSON representation.
+func newJSONBandSummary(row multiBandRow) jsonBandSummary {
+	summary := jsonBandSummary{Samples: row.aggregate.Samples, Groups: row.percentileRank.Groups, DuplicatesRemoved: row.duplicatesRemoved}
+	if row.aggregate.Valid() {
+		summary.DbMedian = &row.aggregate.DbMedian
+	}
+	if row.percentileRank.Groups > 0 {
+		summary.PercentileRank = &row.percentileRank.Mean
+	}
+	return summary
+}
+
+// Write a multi-band result as a JSON document.
+func writeMultiBandJSON(w io.Writer, result *MultiBandResult) error {
+	rows := multiBandRows(result)
+	doc := jsonMultiBandDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Target:        result.TargetCallsign,
+		StartTime:     result.StartTime.UTC().Format(time.RFC3339),
+		EndTime:       result.StartTime.Add(result.Duration).UTC().Format(time.RFC3339),
+		NormPower_dBm: result.NormTxPwr_dBm,
+		Duplicates:    jsonDuplicates{Policy: result.Duplicates.String(), Removed: result.DuplicatesRemoved},
+		Bands:         make([]jsonBandSummary, 0, len(result.Bands)),
+		Combined:      newJSONBandSummary(rows[len(rows)-1]),
+	}
+	for i, bandResult := range result.Bands {
+		summary := newJSONBandSummary(rows[i])
+		summary.Band = &bandResult.Band
+		summary.BandName = rows[i].name
+		doc.Bands = append(doc.Bands, summary)
+	}
+	if confidence := result.Combined.Confidence; confidence.Valid {
+		doc.Confidence = &jsonConfidence{Low: confidence.Low, High: confidence.High, Level: confidence.Level}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON output (%w)", err)
+	}
+	return nil
+}
+
+// Write a multi-band result as CSV with one row per band and a final
+// "combined" row. Empty fields mean the value could not be calculated.
+func writeMultiBandCSV(w io.Writer, result *MultiBandResult, comma rune) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+	csvWriter.Write([]string{"band", "db_median", "samples", "groups", "percentile_rank", "duplicates_removed"})
+	for _, row := range multiBandRows(result) {
+		dbMedian, percentile := "", ""
+		if row.aggregate.Valid() {
+			dbMedian = strconv.FormatFloat(row.aggregate.DbMedian, 'f', 1, 64)
+		}
+		if row.percentileRank.Groups > 0 {
+			percentile = strconv.FormatFloat(row.percentileRank.Mean, 'f', 1, 64)
+		}
+		csvWriter.Write([]string{row.name, dbMedian, strconv.Itoa(row.aggregate.Samples), strconv.Itoa(row.percentileRank.Groups), percentile,
+			strconv.Itoa(row.duplicatesRemoved)})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output (%w)", err)
+	}
+	return nil
+}
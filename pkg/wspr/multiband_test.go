@@ -0,0 +1,157 @@
+package wspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bandSource is a ReportSource which returns canned reports for each band.
+type bandSource struct {
+	mu      sync.Mutex
+	reports map[int][]ReceptionReport
+	bands   []int
+}
+
+func (s *bandSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bands = append(s.bands, band)
+	return s.reports[band], nil
+}
+
+// Reports on 20m with W5XYZ 5dB above two others and on 40m with W5XYZ 3dB
+// below two others.
+var multiBandReports = map[int][]ReceptionReport{
+	14: {
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 200},
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "G3ABC", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+	},
+	7: {
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5DEF", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -18, Distance_km: 200},
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+		{TimeStr: "2024-12-14 10:00:00", RxSign: "W5DEF", TxSign: "G3ABC", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+	},
+}
+
+// TestRunMultiBandAnalysis tests the per-band and combined results, including
+// a band with no reports.
+func TestRunMultiBandAnalysis(t *testing.T) {
+	source := &bandSource{reports: multiBandReports}
+
+	result, err := RunMultiBandAnalysis(source, testParams, []int{7, 14, 21})
+
+	if err != nil {
+		t.Fatalf("RunMultiBandAnalysis() unexpected error: %v", err)
+	}
+	if len(source.bands) != 3 {
+		t.Errorf("RunMultiBandAnalysis() fetched bands %v, want 3", source.bands)
+	}
+	if len(result.Bands) != 3 || result.Bands[0].Band != 7 || result.Bands[2].Band != 21 {
+		t.Fatalf("RunMultiBandAnalysis() bands = %+v, want 7, 14 and 21 in order", result.Bands)
+	}
+	if r := result.Bands[0].Result; r == nil || r.Aggregate.DbMedian != -3 {
+		t.Errorf("RunMultiBandAnalysis() 40m result = %+v, want -3dB", r)
+	}
+	if r := result.Bands[1].Result; r == nil || r.Aggregate.DbMedian != 5 {
+		t.Errorf("RunMultiBandAnalysis() 20m result = %+v, want +5dB", r)
+	}
+	if result.Bands[2].Result != nil {
+		t.Errorf("RunMultiBandAnalysis() 15m result = %+v, want nil", result.Bands[2].Result)
+	}
+	// Combined samples are +5, +5, -3 and -3.
+	if result.Combined.Samples != 4 || result.Combined.DbMedian != 1 {
+		t.Errorf("RunMultiBandAnalysis() combined = %+v, want +1dB from 4 samples", result.Combined)
+	}
+	if result.CombinedPercentileRank.Groups != 2 || result.CombinedPercentileRank.Mean != 50 {
+		t.Errorf("RunMultiBandAnalysis() combined percentile rank = %+v, want 50 from 2 groups", result.CombinedPercentileRank)
+	}
+}
+
+// TestRunMultiBandAnalysis_NoReports tests that it is an error if no band has
+// any reports.
+func TestRunMultiBandAnalysis_NoReports(t *testing.T) {
+	_, err := RunMultiBandAnalysis(&bandSource{}, testParams, []int{7, 14})
+
+	if !errors.Is(err, ErrNoReports) {
+		t.Errorf("RunMultiBandAnalysis() error = %v, want wrapped ErrNoReports", err)
+	}
+}
+
+// TestRunMultiBandAnalysis_AllFilteredOut tests that a result without a
+// combined summary, not ErrNoReports, is returned when there were reports but
+// every group was filtered out.
+func TestRunMultiBandAnalysis_AllFilteredOut(t *testing.T) {
+	params := testParams
+	params.Filter = &FilterChain{MinComparables: 3}
+
+	result, err := RunMultiBandAnalysis(&bandSource{reports: multiBandReports}, params, []int{7, 14})
+
+	if err != nil {
+		t.Fatalf("RunMultiBandAnalysis() unexpected error: %v", err)
+	}
+	for _, bandResult := range result.Bands {
+		if bandResult.Result == nil || len(bandResult.Result.Groups) != 0 {
+			t.Errorf("RunMultiBandAnalysis() band %d result = %+v, want no groups", bandResult.Band, bandResult.Result)
+		}
+	}
+	if result.Combined.Valid() || result.CombinedPercentileRank.Groups != 0 {
+		t.Errorf("RunMultiBandAnalysis() combined = %+v, %+v, want no summary", result.Combined, result.CombinedPercentileRank)
+	}
+
+	var buf bytes.Buffer
+	WriteMultiBand(&buf, result, FormatText)
+	if want := "    combined           -        0        0           -"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WriteMultiBand(text) output missing %q:\n%s", want, buf.String())
+	}
+}
+
+// TestWriteMultiBand tests each output format.
+func TestWriteMultiBand(t *testing.T) {
+	result, err := RunMultiBandAnalysis(&bandSource{reports: multiBandReports}, testParams, []int{7, 14, 21})
+	if err != nil {
+		t.Fatalf("RunMultiBandAnalysis() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	WriteMultiBand(&buf, result, FormatText)
+	for _, want := range []string{"    40m             -3.0        2        1        0.0%", "    15m                -        0        0           -", "    combined        +1.0        4        2       50.0%"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMultiBand(text) output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	WriteMultiBand(&buf, result, FormatCSV)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 5 || lines[1] != "40m,-3.0,2,1,0.0,0" || lines[3] != "15m,,0,0,,0" || lines[4] != "combined,1.0,4,2,50.0,0" {
+		t.Errorf("WriteMultiBand(csv) output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	WriteMultiBand(&buf, result, FormatJSON)
+	var doc struct {
+		Bands []struct {
+			Band     int      `json:"band"`
+			BandName string   `json:"band_name"`
+			DbMedian *float64 `json:"db_median"`
+		} `json:"bands"`
+		Combined struct {
+			DbMedian *float64 `json:"db_median"`
+			Samples  int      `json:"samples"`
+		} `json:"combined"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteMultiBand(json) produced invalid JSON: %v", err)
+	}
+	if len(doc.Bands) != 3 || doc.Bands[1].BandName != "20m" || doc.Bands[1].Band != 14 || doc.Bands[2].DbMedian != nil {
+		t.Errorf("WriteMultiBand(json) bands = %+v", doc.Bands)
+	}
+	if doc.Combined.DbMedian == nil || *doc.Combined.DbMedian != 1 || doc.Combined.Samples != 4 {
+		t.Errorf("WriteMultiBand(json) combined = %+v", doc.Combined)
+	}
+}
@@ -0,0 +1,156 @@
+// This file contains the detection of receivers whose SNR reports are
+// inconsistent with those of the other receivers.
+
+package wspr
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// OutlierMode selects what happens to receivers detected as outliers.
+type OutlierMode int
+
+const (
+	OutliersOff OutlierMode = iota
+	OutliersWarn
+	OutliersDrop
+)
+
+// Map between outlier mode names (as used on the command line) and their
+// OutlierMode values.
+var outlierModeNames = map[string]OutlierMode{
+	"off":  OutliersOff,
+	"warn": OutliersWarn,
+	"drop": OutliersDrop,
+}
+
+// Return all the outlier mode names (useful for the CLI help text).
+func OutlierModeNames() []string {
+	names := make([]string, 0, len(outlierModeNames))
+	for k := range outlierModeNames {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Convert an outlier mode name to its OutlierMode. Returns an error if the
+// name is not recognised.
+func ParseOutlierMode(name string) (OutlierMode, error) {
+	if mode, ok := outlierModeNames[strings.ToLower(name)]; ok {
+		return mode, nil
+	}
+	return 0, fmt.Errorf("unrecognised outlier mode: %s", name)
+}
+
+// Default robust z-score beyond which a receiver is an outlier. This is the
+// usual cut-off for the modified z-score of Iglewicz and Hoaglin.
+const DefaultOutlierThreshold = 3.5
+
+// Receivers with fewer samples than this, and analyses with fewer eligible
+// receivers than this, are not assessed.
+const minOutlierSamples = 5
+
+// How widely the normalised SNRs reported by a receiver are spread. Spread_dB
+// is the median absolute deviation of each report's normalised SNR from the
+// median of its report group, over all the receiver's groups. The target's
+// reports are left out, since how the target differs from the others is what
+// the analysis measures. Score is the robust z-score of Spread_dB among all
+// the receivers: large positive scores mean implausibly scattered reports,
+// large negative ones mean reports clustered at one SNR.
+type ReceiverSpread struct {
+	RxSign    string
+	Samples   int
+	Spread_dB float64
+	Score     float64
+}
+
+// Calculate the robust z-scores of values: 0.6745 times the deviation from the
+// median divided by the median absolute deviation (MAD). If the MAD is zero,
+// 1.2533 times the mean absolute deviation is used instead. Returns nil if the
+// values do not vary.
+func robustZScores(values []float64) []float64 {
+	centre, _ := median(slices.Clone(values), false)
+	deviations := make([]float64, len(values))
+	var totalDeviation float64
+	for i, value := range values {
+		deviations[i] = math.Abs(value - centre)
+		totalDeviation += deviations[i]
+	}
+	mad, _ := median(slices.Clone(deviations), false)
+	scale := mad / 0.6745
+	if mad == 0 {
+		scale = 1.2533 * totalDeviation / float64(len(values))
+	}
+	if scale == 0 {
+		return nil
+	}
+	scores := make([]float64, len(values))
+	for i, value := range values {
+		scores[i] = (value - centre) / scale
+	}
+	return scores
+}
+
+// DetectOutlierReceivers returns the receivers whose ReceiverSpread has a
+// robust z-score beyond threshold (in either direction), sorted by callsign.
+// rxReports would normally have been filtered, so that each report group
+// only contains transmitters at a similar distance, and the reports in each
+// group must be sorted by descending normalised SNR.
+func DetectOutlierReceivers(rxReports []ReceptionReportGroup, normTxPwr_dBm int8, threshold float64) []ReceiverSpread {
+	deviationsByReceiver := make(map[string][]float64)
+	for _, reportGroup := range rxReports {
+		snrNorms := make([]float64, 0, len(reportGroup.Reports)-1)
+		for i, report := range reportGroup.Reports {
+			if i != reportGroup.TargetIndex {
+				snrNorms = append(snrNorms, float64(report.SnrNorm_dB(normTxPwr_dBm)))
+			}
+		}
+		if len(snrNorms) == 0 {
+			continue
+		}
+		groupMedian, _ := median(slices.Clone(snrNorms), false)
+		for _, snrNorm := range snrNorms {
+			deviationsByReceiver[reportGroup.RxSign] = append(deviationsByReceiver[reportGroup.RxSign], math.Abs(snrNorm-groupMedian))
+		}
+	}
+	var receivers []ReceiverSpread
+	for rxSign, deviations := range deviationsByReceiver {
+		if len(deviations) >= minOutlierSamples {
+			spread, _ := median(deviations, false)
+			receivers = append(receivers, ReceiverSpread{RxSign: rxSign, Samples: len(deviations), Spread_dB: spread})
+		}
+	}
+	if len(receivers) < minOutlierSamples {
+		return nil
+	}
+	spreads := make([]float64, len(receivers))
+	for i, receiver := range receivers {
+		spreads[i] = receiver.Spread_dB
+	}
+	scores := robustZScores(spreads)
+	var outliers []ReceiverSpread
+	for i, score := range scores {
+		if math.Abs(score) > threshold {
+			receivers[i].Score = score
+			outliers = append(outliers, receivers[i])
+		}
+	}
+	slices.SortFunc(outliers, func(a, b ReceiverSpread) int {
+		return cmp.Compare(a.RxSign, b.RxSign)
+	})
+	return outliers
+}
+
+// Remove the report groups of the given receivers.
+func dropReceivers(rxReports []ReceptionReportGroup, receivers []ReceiverSpread) []ReceptionReportGroup {
+	return slices.DeleteFunc(rxReports, func(reportGroup ReceptionReportGroup) bool {
+		return slices.ContainsFunc(receivers, func(receiver ReceiverSpread) bool {
+			return receiver.RxSign == reportGroup.RxSign
+		})
+	})
+}
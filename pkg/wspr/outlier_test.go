@@ -0,0 +1,165 @@
+package wspr
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// spreadGroup creates a report group for rxSign in which the target and the
+// other transmitters have the given SNRs (all at the same power).
+func spreadGroup(rxSign string, snrs ...int8) ReceptionReportGroup {
+	reports := make([]ReceptionReport, len(snrs))
+	for i, snr := range snrs {
+		reports[i] = ReceptionReport{RxSign: rxSign, TxSign: fmt.Sprintf("TX%d", i), Power_dBm: 43, Snr_dB: snr}
+	}
+	return ReceptionReportGroup{RxSign: rxSign, Reports: reports}
+}
+
+// TestParseOutlierMode tests the outlier mode names.
+func TestParseOutlierMode(t *testing.T) {
+	for name, want := range map[string]OutlierMode{"off": OutliersOff, "WARN": OutliersWarn, "drop": OutliersDrop} {
+		if mode, err := ParseOutlierMode(name); err != nil || mode != want {
+			t.Errorf("ParseOutlierMode(%q) = %v, %v, want %v", name, mode, err, want)
+		}
+	}
+	if _, err := ParseOutlierMode("ignore"); err == nil {
+		t.Errorf("ParseOutlierMode(\"ignore\") expected error, got nil")
+	}
+}
+
+// TestRobustZScores tests the MAD scaling and the mean absolute deviation
+// fallback.
+func TestRobustZScores(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{
+			// Median 3, MAD 1.
+			name:   "MAD",
+			values: []float64{1, 2, 3, 4, 10},
+			want:   []float64{-2 * 0.6745, -0.6745, 0, 0.6745, 7 * 0.6745},
+		},
+		{
+			// Median 2, MAD 0, mean absolute deviation 8/5.
+			name:   "zero MAD",
+			values: []float64{2, 2, 2, 2, 10},
+			want:   []float64{0, 0, 0, 0, 8 / (1.2533 * 1.6)},
+		},
+		{
+			name:   "constant",
+			values: []float64{2, 2, 2},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := robustZScores(tt.values)
+			if len(scores) != len(tt.want) {
+				t.Fatalf("robustZScores() = %v, want %v", scores, tt.want)
+			}
+			for i := range tt.want {
+				if math.Abs(scores[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("robustZScores() = %v, want %v", scores, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// Report groups from five consistent receivers (with spreads of 5-7dB), one
+// which reports every transmitter at the same SNR and one which scatters them
+// widely. Each receiver has two groups, in which the target is the first
+// transmitter.
+func outlierTestGroups() []ReceptionReportGroup {
+	var rxReports []ReceptionReportGroup
+	for i, rxSign := range []string{"W1AAA", "W2BBB", "W3CCC", "W4DDD", "W5EEE"} {
+		spread := []int8{5, 6, 6, 7, 5}[i]
+		rxReports = append(rxReports, spreadGroup(rxSign, -10, -10-spread, -10-2*spread, -10-3*spread), spreadGroup(rxSign, -9, -9-spread, -9-2*spread, -9-3*spread))
+	}
+	rxReports = append(rxReports, spreadGroup("K1FLAT", -10, -10, -10, -10), spreadGroup("K1FLAT", -10, -10, -10, -10))
+	rxReports = append(rxReports, spreadGroup("K1WILD", 10, -10, -30, -50), spreadGroup("K1WILD", 15, -10, -35, -60))
+	return rxReports
+}
+
+// TestDetectOutlierReceivers tests that both clustered and scattered
+// receivers are detected.
+func TestDetectOutlierReceivers(t *testing.T) {
+	outliers := DetectOutlierReceivers(outlierTestGroups(), 43, DefaultOutlierThreshold)
+
+	if len(outliers) != 2 || outliers[0].RxSign != "K1FLAT" || outliers[1].RxSign != "K1WILD" {
+		t.Fatalf("DetectOutlierReceivers() = %+v, want K1FLAT and K1WILD", outliers)
+	}
+	if outliers[0].Score >= 0 || outliers[0].Spread_dB != 0 || outliers[0].Samples != 6 {
+		t.Errorf("DetectOutlierReceivers() K1FLAT = %+v, want negative score and zero spread", outliers[0])
+	}
+	if outliers[1].Score <= 0 || outliers[1].Spread_dB != 20 {
+		t.Errorf("DetectOutlierReceivers() K1WILD = %+v, want positive score and 20dB spread", outliers[1])
+	}
+}
+
+// TestDetectOutlierReceivers_TargetDeviates tests that a receiver is not
+// flagged when only the target differs from the other transmitters.
+func TestDetectOutlierReceivers_TargetDeviates(t *testing.T) {
+	// The other transmitters have a spread of 6dB, but the target is far from
+	// them.
+	rxReports := outlierTestGroups()[:10]
+	for _, targetSnr := range []int8{20, -50, 20} {
+		rxReports = append(rxReports, spreadGroup("K1TGT", targetSnr, -16, -28))
+	}
+
+	if outliers := DetectOutlierReceivers(rxReports, 43, DefaultOutlierThreshold); outliers != nil {
+		t.Errorf("DetectOutlierReceivers() = %+v, want nil", outliers)
+	}
+}
+
+// TestDetectOutlierReceivers_TooFewReceivers tests that nothing is flagged
+// without enough receivers to compare.
+func TestDetectOutlierReceivers_TooFewReceivers(t *testing.T) {
+	rxReports := outlierTestGroups()[6:]
+
+	if outliers := DetectOutlierReceivers(rxReports, 43, DefaultOutlierThreshold); outliers != nil {
+		t.Errorf("DetectOutlierReceivers() = %+v, want nil", outliers)
+	}
+}
+
+// TestRunAnalysis_Outliers tests the warn and drop modes.
+func TestRunAnalysis_Outliers(t *testing.T) {
+	var reports []ReceptionReport
+	for i, group := range outlierTestGroups() {
+		for _, report := range group.Reports {
+			report.TimeStr = fmt.Sprintf("2024-12-14 15:%02d:00", 30+2*(i%2))
+			report.Distance_km = 1000
+			if report.TxSign == "TX0" {
+				report.TxSign = "W5XYZ"
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	for _, tt := range []struct {
+		mode       OutlierMode
+		wantGroups int
+	}{
+		{mode: OutliersOff, wantGroups: 14},
+		{mode: OutliersWarn, wantGroups: 14},
+		{mode: OutliersDrop, wantGroups: 10},
+	} {
+		params := testParams
+		params.OutlierMode = tt.mode
+		result, err := RunAnalysis(&fakeSource{reports: reports}, params)
+		if err != nil {
+			t.Fatalf("RunAnalysis() unexpected error: %v", err)
+		}
+		if len(result.Groups) != tt.wantGroups {
+			t.Errorf("RunAnalysis() mode %v gave %d groups, want %d", tt.mode, len(result.Groups), tt.wantGroups)
+		}
+		if wantOutliers := tt.mode != OutliersOff; (len(result.Outliers) == 2) != wantOutliers {
+			t.Errorf("RunAnalysis() mode %v outliers = %+v", tt.mode, result.Outliers)
+		}
+	}
+}
@@ -0,0 +1,535 @@
+// This file contains the code for writing out analysis results in the
+// supported output formats.
+
+package wspr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutputFormat selects how the analysis results are written out.
+type OutputFormat int
+
+const (
+	FormatText OutputFormat = iota
+	FormatJSON
+	FormatCSV
+	FormatTSV
+)
+
+// Map between output format names (as used on the command line) and their
+// OutputFormat values.
+var outputFormatNames = map[string]OutputFormat{
+	"text": FormatText,
+	"json": FormatJSON,
+	"csv":  FormatCSV,
+	"tsv":  FormatTSV,
+}
+
+// Return all the output format names (useful for the CLI help text).
+func OutputFormatNames() []string {
+	names := make([]string, 0, len(outputFormatNames))
+	for k := range outputFormatNames {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Convert an output format name to its OutputFormat. Returns an error if the
+// name is not recognised.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	if format, ok := outputFormatNames[strings.ToLower(name)]; ok {
+		return format, nil
+	}
+	return 0, fmt.Errorf("unrecognised output format: %s", name)
+}
+
+// Options which control the text output format.
+type TextOptions struct {
+	// List every transmitter in each report group.
+	Verbose bool
+	// Draw bar charts of any breakdowns as well as tables.
+	Charts bool
+}
+
+// WriteResult writes result to w in the given format. textOptions only
+// affects the text format.
+func WriteResult(w io.Writer, result *AnalysisResult, format OutputFormat, textOptions TextOptions) error {
+	switch format {
+	case FormatJSON:
+		return WriteResultJSON(w, result)
+	case FormatCSV:
+		return WriteResultCSV(w, result, ',')
+	case FormatTSV:
+		return WriteResultCSV(w, result, '\t')
+	default:
+		return WriteResultText(w, result, textOptions)
+	}
+}
+
+// WriteResultText writes the reception reports nicely formatted for the
+// console, along with the statistics showing how the target transmitter
+// compares with the rest.
+func WriteResultText(w io.Writer, result *AnalysisResult, options TextOptions) error {
+	if len(result.TargetLocators) > 1 {
+		fmt.Fprintf(w, "%s reported from %d locators, which are different paths", result.TargetCallsign, len(result.TargetLocators))
+		if result.TargetLocator != "" {
+			fmt.Fprintf(w, "; only using %s\n", result.TargetLocator)
+		} else {
+			fmt.Fprintf(w, "; the results mix them\n")
+		}
+		for _, span := range result.TargetLocators {
+			fmt.Fprintf(w, "    %s from %s to %s (%d groups)\n", span.Locator,
+				span.First.UTC().Format(time.RFC3339), span.Last.UTC().Format(time.RFC3339), span.Groups)
+		}
+	}
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	for _, receiver := range result.Excluded.Receivers {
+		fmt.Fprintf(w, "Reports from %s excluded (%d time slots)\n", receiver.Callsign, receiver.Count)
+	}
+	for _, transmitter := range result.Excluded.Transmitters {
+		fmt.Fprintf(w, "Transmitter %s excluded from comparisons (%d reports)\n", transmitter.Callsign, transmitter.Count)
+	}
+	for _, receiver := range result.Outliers {
+		action := "kept"
+		if result.OutlierMode == OutliersDrop {
+			action = "dropped"
+		}
+		fmt.Fprintf(w, "Reports from %s look unreliable (SNR spread %.1fdB, robust z-score %+.1f, %d samples); %s\n",
+			receiver.RxSign, receiver.Spread_dB, receiver.Score, receiver.Samples, action)
+	}
+	for _, reportGroup := range result.FilteredOut {
+		fmt.Fprintf(w, "Reports from %s at %s filtered out due to insufficient comparable transmitters\n", reportGroup.RxSign, reportGroup.Time.UTC().Format(time.RFC3339))
+	}
+	for _, group := range result.Groups {
+		fmt.Fprintf(w, "Received by %s (distance %dkm) at %s:\n", group.RxSign, group.Reports[group.TargetIndex].Distance_km,
+			group.Time.UTC().Format("2006-01-02T15:04:05Z07:00"))
+		if options.Verbose {
+			for i, report := range group.Reports {
+				if i == group.TargetIndex {
+					fmt.Fprintf(w, "     -->")
+				} else {
+					fmt.Fprintf(w, "        ")
+				}
+				fmt.Fprintf(w, "%d: Transmitter: %s, Power: %ddBm, Distance: %dkm, RX Azimuth: %dº, SNR: %+ddB, Normalised SNR: %+ddB", i+1,
+					report.TxSign, report.Power_dBm, report.Distance_km, report.RxAzimuth, report.Snr_dB, report.SnrNorm_dB(result.NormTxPwr_dBm))
+				if report.TxLoc != "" {
+					fmt.Fprintf(w, ", Locator: %s", report.TxLoc)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+		if len(group.Reports) > 1 {
+			fmt.Fprintf(w, "    %d out of %d transmitters; Normalised SNR: %+ddB, %+ddBmedian, percentile rank %.0f%%\n", group.Rank, len(group.Reports),
+				group.TargetSnrNorm_dB, group.SnrNormOverMedian_dB, group.PercentileRank)
+		}
+	}
+	fmt.Fprintf(w, "\nOffset from median of relative normalised SNR of all other transmitters: ")
+	if result.Aggregate.Valid() {
+		fmt.Fprintf(w, "%+.1fdBmedian", result.Aggregate.DbMedian)
+		if result.BootstrapIterations > 0 {
+			fmt.Fprintf(w, ", %v (bootstrap, %d iterations)", result.Aggregate.Confidence, result.BootstrapIterations)
+		}
+		fmt.Fprintf(w, " (%d samples)\n", result.Aggregate.Samples)
+	}
+	if result.PercentileRank.Groups > 0 {
+		fmt.Fprintf(w, "Mean percentile rank among comparable transmitters: %.1f%% (%d groups)\n", result.PercentileRank.Mean, result.PercentileRank.Groups)
+	}
+	if len(result.StrategyAggregates) > 0 && result.Aggregate.Valid() {
+		fmt.Fprintf(w, "\nAggregate metric by strategy:\n")
+		for _, strategyAggregate := range result.StrategyAggregates {
+			fmt.Fprintf(w, "    %-14s %+6.1fdBmedian", strategyAggregate.Strategy, strategyAggregate.Aggregate.DbMedian)
+			if strategyAggregate.Strategy == result.Aggregation {
+				fmt.Fprintf(w, " (selected)")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	for _, breakdown := range result.Breakdowns {
+		writeBreakdownText(w, breakdown)
+		if options.Charts {
+			writeBreakdownChart(w, breakdown)
+		}
+	}
+	return nil
+}
+
+// Write a breakdown of the results as a table.
+func writeBreakdownText(w io.Writer, breakdown Breakdown) {
+	fmt.Fprintf(w, "\nBreakdown by %s (%s):\n", breakdown.Name, breakdown.Metric)
+	fmt.Fprintf(w, "    %-16s %8s %8s %8s\n", "", "Median", "IQR", "Samples")
+	for _, bin := range breakdown.Bins {
+		if bin.Samples == 0 {
+			fmt.Fprintf(w, "    %-16s %8s %8s %8d\n", bin.Label, "-", "-", 0)
+		} else {
+			fmt.Fprintf(w, "    %-16s %+6.1fdB %6.1fdB %8d\n", bin.Label, bin.Median_dB, bin.IQR_dB, bin.Samples)
+		}
+	}
+}
+
+// Print out the reception reports nicely formatted to the console. Also perform some
+// basic stats to show how the target transmitter compares with the rest.
+func PrintReportsAndStats(rxReports []ReceptionReportGroup, targetCallsign string, normTxPwr_dBm int8, verbose bool) {
+	params := AnalysisParams{TargetCallsign: targetCallsign, NormTxPwr_dBm: normTxPwr_dBm}
+	WriteResultText(os.Stdout, AnalyseReports(params, rxReports, nil), TextOptions{Verbose: verbose})
+}
+
+// Write the medians of a breakdown as a horizontal bar chart, with negative
+// values extending left of the axis and positive values to the right.
+func writeBreakdownChart(w io.Writer, breakdown Breakdown) {
+	const halfWidth = 20
+	var scale float64
+	for _, bin := range breakdown.Bins {
+		if bin.Samples > 0 {
+			scale = max(scale, math.Abs(bin.Median_dB))
+		}
+	}
+	fmt.Fprintln(w)
+	for _, bin := range breakdown.Bins {
+		left, right := strings.Repeat(" ", halfWidth), ""
+		value := "-"
+		if bin.Samples > 0 {
+			length := 0
+			if scale > 0 {
+				length = int(math.Round(math.Abs(bin.Median_dB) / scale * halfWidth))
+			}
+			if bin.Median_dB < 0 {
+				left = strings.Repeat(" ", halfWidth-length) + strings.Repeat("#", length)
+			} else {
+				right = strings.Repeat("#", length)
+			}
+			value = fmt.Sprintf("%+.1fdB", bin.Median_dB)
+		}
+		fmt.Fprintf(w, "    %-16s %s|%-*s %s\n", bin.Label, left, halfWidth, right, value)
+	}
+}
+
+// Version of the JSON document written by WriteResultJSON. This is
+// incremented whenever a field is removed or its meaning changes; new fields
+// may be added without changing the version.
+const JSONSchemaVersion = 1
+
+// Top level of the JSON output document.
+type jsonDocument struct {
+	SchemaVersion int               `json:"schema_version"`
+	Target        string            `json:"target"`
+	Band          int               `json:"band"`
+	StartTime     string            `json:"start_time"`
+	EndTime       string            `json:"end_time"`
+	NormPower_dBm int8              `json:"norm_power_dbm"`
+	TargetLoc     string            `json:"target_loc,omitempty"`
+	Locators      []jsonLocatorSpan `json:"target_locators"`
+	Duplicates    jsonDuplicates    `json:"duplicates"`
+	Groups        []jsonReportGroup `json:"groups"`
+	FilteredOut   []jsonFilteredOut `json:"filtered_out"`
+	Excluded      jsonExcluded      `json:"excluded"`
+	Outliers      []jsonOutlier     `json:"outliers"`
+	Aggregate     jsonAggregate     `json:"aggregate"`
+	Strategies    []jsonStrategy    `json:"strategies"`
+	Percentile    jsonPercentile    `json:"percentile_rank"`
+	Breakdowns    []jsonBreakdown   `json:"breakdowns,omitempty"`
+}
+
+// JSON representation of a Breakdown.
+type jsonBreakdown struct {
+	Name   string             `json:"name"`
+	Metric string             `json:"metric"`
+	Bins   []jsonBreakdownBin `json:"bins"`
+}
+
+// JSON representation of a BreakdownBin. The statistics are null if the bin
+// is empty, and High is null if the bin is unbounded.
+type jsonBreakdownBin struct {
+	Label     string   `json:"label"`
+	Low       float64  `json:"low"`
+	High      *float64 `json:"high"`
+	Median_dB *float64 `json:"median_db"`
+	IQR_dB    *float64 `json:"iqr_db"`
+	Samples   int      `json:"samples"`
+}
+
+// JSON representation of a LocatorSpan.
+type jsonLocatorSpan struct {
+	Locator string `json:"locator"`
+	First   string `json:"first"`
+	Last    string `json:"last"`
+	Groups  int    `json:"groups"`
+}
+
+// A group which was dropped for lack of comparable transmitters.
+type jsonFilteredOut struct {
+	RxSign string `json:"rx_sign"`
+	Time   string `json:"time"`
+}
+
+// The receivers and transmitters removed by the exclusion lists, with the
+// number of time slots or reports removed for each.
+type jsonExcluded struct {
+	Receivers    []jsonExcludedCallsign `json:"receivers"`
+	Transmitters []jsonExcludedCallsign `json:"transmitters"`
+}
+
+// JSON representation of the merging of duplicate spots.
+type jsonDuplicates struct {
+	Policy  string `json:"policy"`
+	Removed int    `json:"removed"`
+}
+
+// JSON representation of an ExcludedCallsign.
+type jsonExcludedCallsign struct {
+	Callsign string `json:"callsign"`
+	Count    int    `json:"count"`
+}
+
+// Convert excluded callsigns to their JSON representation.
+func newJSONExcludedCallsigns(excluded []ExcludedCallsign) []jsonExcludedCallsign {
+	jsonExcluded := make([]jsonExcludedCallsign, 0, len(excluded))
+	for _, callsign := range excluded {
+		jsonExcluded = append(jsonExcluded, jsonExcludedCallsign{Callsign: callsign.Callsign, Count: callsign.Count})
+	}
+	return jsonExcluded
+}
+
+// A receiver detected as an outlier, and whether its reports were dropped.
+type jsonOutlier struct {
+	RxSign    string  `json:"rx_sign"`
+	Samples   int     `json:"samples"`
+	Spread_dB float64 `json:"spread_db"`
+	Score     float64 `json:"score"`
+	Dropped   bool    `json:"dropped"`
+}
+
+// JSON representation of a ReceptionReportGroup and its statistics. Rank is
+// the 1-based position of the target when the group is ordered by descending
+// normalised SNR.
+type jsonReportGroup struct {
+	RxSign           string       `json:"rx_sign"`
+	RxLoc            string       `json:"rx_loc"`
+	Time             string       `json:"time"`
+	Rank             int          `json:"rank"`
+	TransmitterCount int          `json:"transmitter_count"`
+	Target           jsonReport   `json:"target"`
+	DbOverMedian     int8         `json:"db_over_median"`
+	PercentileRank   float64      `json:"percentile_rank"`
+	Comparables      []jsonReport `json:"comparables"`
+}
+
+// JSON representation of a single ReceptionReport.
+type jsonReport struct {
+	TxSign        string `json:"tx_sign"`
+	Power_dBm     int8   `json:"power_dbm"`
+	Snr_dB        int8   `json:"snr_db"`
+	SnrNorm_dB    int8   `json:"snr_norm_db"`
+	Distance_km   uint16 `json:"distance_km"`
+	TxAzimuth_deg uint16 `json:"azimuth_deg"`
+	RxAzimuth_deg uint16 `json:"rx_azimuth_deg"`
+	TxLoc         string `json:"tx_loc"`
+	Frequency_Hz  uint32 `json:"frequency_hz"`
+}
+
+// The aggregate metric across all groups, calculated with the selected
+// Strategy. DbMedian is null if there are too few samples to calculate it,
+// and Confidence is null if bootstrapping was disabled or the interval could
+// not be calculated.
+type jsonAggregate struct {
+	Strategy   string          `json:"strategy"`
+	DbMedian   *float64        `json:"db_median"`
+	Samples    int             `json:"samples"`
+	Confidence *jsonConfidence `json:"confidence"`
+}
+
+// The aggregate metric calculated with one strategy. DbMedian is null if
+// there are too few samples to calculate it.
+type jsonStrategy struct {
+	Strategy string   `json:"strategy"`
+	DbMedian *float64 `json:"db_median"`
+}
+
+// The mean of the per-group percentile ranks. Mean is null if there are no
+// groups.
+type jsonPercentile struct {
+	Mean   *float64 `json:"mean"`
+	Groups int      `json:"groups"`
+}
+
+// A confidence interval.
+type jsonConfidence struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Level float64 `json:"level"`
+}
+
+// Convert a ReceptionReport to its JSON representation.
+func newJSONReport(report ReceptionReport, normTxPwr_dBm int8) jsonReport {
+	return jsonReport{
+		TxSign:        report.TxSign,
+		Power_dBm:     report.Power_dBm,
+		Snr_dB:        report.Snr_dB,
+		SnrNorm_dB:    report.SnrNorm_dB(normTxPwr_dBm),
+		Distance_km:   report.Distance_km,
+		TxAzimuth_deg: report.TxAzimuth,
+		RxAzimuth_deg: report.RxAzimuth,
+		TxLoc:         report.TxLoc,
+		Frequency_Hz:  report.Frequency_Hz,
+	}
+}
+
+// WriteResultJSON writes the filtered reception reports, the per-group
+// statistics and the aggregate metric to w as a JSON document (see
+// JSONSchemaVersion).
+func WriteResultJSON(w io.Writer, result *AnalysisResult) error {
+	doc := jsonDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Target:        result.TargetCallsign,
+		Band:          result.Band,
+		StartTime:     result.StartTime.UTC().Format(time.RFC3339),
+		EndTime:       result.StartTime.Add(result.Duration).UTC().Format(time.RFC3339),
+		NormPower_dBm: result.NormTxPwr_dBm,
+		TargetLoc:     result.TargetLocator,
+		Locators:      make([]jsonLocatorSpan, 0, len(result.TargetLocators)),
+		Duplicates:    jsonDuplicates{Policy: result.Duplicates.String(), Removed: result.DuplicatesRemoved},
+		Groups:        make([]jsonReportGroup, 0, len(result.Groups)),
+		FilteredOut:   make([]jsonFilteredOut, 0, len(result.FilteredOut)),
+		Outliers:      make([]jsonOutlier, 0, len(result.Outliers)),
+		Excluded: jsonExcluded{
+			Receivers:    newJSONExcludedCallsigns(result.Excluded.Receivers),
+			Transmitters: newJSONExcludedCallsigns(result.Excluded.Transmitters),
+		},
+		Aggregate:  jsonAggregate{Strategy: result.Aggregation.String(), Samples: result.Aggregate.Samples},
+		Strategies: make([]jsonStrategy, 0, len(result.StrategyAggregates)),
+		Percentile: jsonPercentile{Groups: result.PercentileRank.Groups},
+	}
+	if result.PercentileRank.Groups > 0 {
+		doc.Percentile.Mean = &result.PercentileRank.Mean
+	}
+	for _, span := range result.TargetLocators {
+		doc.Locators = append(doc.Locators, jsonLocatorSpan{
+			Locator: span.Locator,
+			First:   span.First.UTC().Format(time.RFC3339),
+			Last:    span.Last.UTC().Format(time.RFC3339),
+			Groups:  span.Groups,
+		})
+	}
+	for _, group := range result.Groups {
+		jsonGroup := jsonReportGroup{
+			RxSign:           group.RxSign,
+			RxLoc:            group.Reports[group.TargetIndex].RxLoc,
+			Time:             group.Time.UTC().Format(time.RFC3339),
+			Rank:             group.Rank,
+			TransmitterCount: len(group.Reports),
+			Target:           newJSONReport(group.Reports[group.TargetIndex], result.NormTxPwr_dBm),
+			DbOverMedian:     group.SnrNormOverMedian_dB,
+			PercentileRank:   group.PercentileRank,
+			Comparables:      make([]jsonReport, 0, len(group.Reports)-1),
+		}
+		for i, report := range group.Reports {
+			if i != group.TargetIndex {
+				jsonGroup.Comparables = append(jsonGroup.Comparables, newJSONReport(report, result.NormTxPwr_dBm))
+			}
+		}
+		doc.Groups = append(doc.Groups, jsonGroup)
+	}
+	for _, strategyAggregate := range result.StrategyAggregates {
+		jsonStrategy := jsonStrategy{Strategy: strategyAggregate.Strategy.String()}
+		if strategyAggregate.Aggregate.Valid() {
+			jsonStrategy.DbMedian = &strategyAggregate.Aggregate.DbMedian
+		}
+		doc.Strategies = append(doc.Strategies, jsonStrategy)
+	}
+	for _, receiver := range result.Outliers {
+		doc.Outliers = append(doc.Outliers, jsonOutlier{
+			RxSign:    receiver.RxSign,
+			Samples:   receiver.Samples,
+			Spread_dB: receiver.Spread_dB,
+			Score:     receiver.Score,
+			Dropped:   result.OutlierMode == OutliersDrop,
+		})
+	}
+	for _, reportGroup := range result.FilteredOut {
+		doc.FilteredOut = append(doc.FilteredOut, jsonFilteredOut{
+			RxSign: reportGroup.RxSign,
+			Time:   reportGroup.Time.UTC().Format(time.RFC3339),
+		})
+	}
+	if result.Aggregate.Valid() {
+		dbMedian := result.Aggregate.DbMedian
+		doc.Aggregate.DbMedian = &dbMedian
+		if confidence := result.Aggregate.Confidence; confidence.Valid {
+			doc.Aggregate.Confidence = &jsonConfidence{Low: confidence.Low, High: confidence.High, Level: confidence.Level}
+		}
+	}
+	for _, breakdown := range result.Breakdowns {
+		jsonBreakdown := jsonBreakdown{Name: breakdown.Name, Metric: breakdown.Metric, Bins: make([]jsonBreakdownBin, 0, len(breakdown.Bins))}
+		for _, bin := range breakdown.Bins {
+			jsonBin := jsonBreakdownBin{Label: bin.Label, Low: bin.Low, Samples: bin.Samples}
+			if !math.IsInf(bin.High, 1) {
+				jsonBin.High = &bin.High
+			}
+			if bin.Samples > 0 {
+				jsonBin.Median_dB, jsonBin.IQR_dB = &bin.Median_dB, &bin.IQR_dB
+			}
+			jsonBreakdown.Bins = append(jsonBreakdown.Bins, jsonBin)
+		}
+		doc.Breakdowns = append(doc.Breakdowns, jsonBreakdown)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON output (%w)", err)
+	}
+	return nil
+}
+
+// Column names for the CSV/TSV output written by WriteResultCSV.
+var csvHeader = []string{
+	"rx_sign", "time",
+	"target_sign", "target_snr_db", "target_power_dbm", "target_snr_norm_db", "target_distance_km", "target_rx_azimuth_deg",
+	"tx_sign", "snr_db", "power_dbm", "snr_norm_db", "distance_km", "rx_azimuth_deg",
+	"relative_snr_norm_db",
+}
+
+// WriteResultCSV writes one row to w for each (receiver, time slot,
+// comparison transmitter) combination in result, preceded by a header row.
+// Each row holds the target and comparison reports side by side, along with
+// the normalised SNR of the comparison transmitter relative to the target
+// (i.e. the samples which make up the aggregate metric). The field separator
+// is comma, so this can also write TSV.
+func WriteResultCSV(w io.Writer, result *AnalysisResult, comma rune) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+	csvWriter.Write(csvHeader)
+	for _, group := range result.Groups {
+		target := group.Reports[group.TargetIndex]
+		for i, report := range group.Reports {
+			if i == group.TargetIndex {
+				continue
+			}
+			csvWriter.Write([]string{
+				group.RxSign, group.Time.UTC().Format(time.RFC3339),
+				target.TxSign, itoa(target.Snr_dB), itoa(target.Power_dBm), itoa(group.TargetSnrNorm_dB), itoa(target.Distance_km), itoa(target.RxAzimuth),
+				report.TxSign, itoa(report.Snr_dB), itoa(report.Power_dBm), itoa(report.SnrNorm_dB(result.NormTxPwr_dBm)), itoa(report.Distance_km), itoa(report.RxAzimuth),
+				itoa(report.SnrNorm_dB(result.NormTxPwr_dBm) - group.TargetSnrNorm_dB),
+			})
+		}
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output (%w)", err)
+	}
+	return nil
+}
+
+// Format an integer field for CSV output.
+func itoa[T int8 | uint16](value T) string {
+	return strconv.Itoa(int(value))
+}
@@ -0,0 +1,333 @@
+package wspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseOutputFormat tests the ParseOutputFormat function.
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		formatName string
+		want       OutputFormat
+		wantError  bool
+	}{
+		{name: "text", formatName: "text", want: FormatText},
+		{name: "json upper case", formatName: "JSON", want: FormatJSON},
+		{name: "unknown", formatName: "xml", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseOutputFormat(tt.formatName)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ParseOutputFormat() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && result != tt.want {
+				t.Errorf("ParseOutputFormat() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteResultJSON tests the structure and values of the JSON output.
+func TestWriteResultJSON(t *testing.T) {
+	targetTime := time.Date(2024, 12, 14, 15, 30, 0, 0, time.UTC)
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			Time:        targetTime,
+			TargetIndex: 1,
+			Reports: []ReceptionReport{
+				{TxSign: "G3ABC", Distance_km: 150, Power_dBm: 30, Snr_dB: -5},  // Normalised: +8
+				{TxSign: "W5XYZ", Distance_km: 200, Power_dBm: 10, Snr_dB: -30}, // Normalised: +3
+				{TxSign: "N0OTH", Distance_km: 225, Power_dBm: 20, Snr_dB: -25}, // Normalised: -2
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	params := AnalysisParams{TargetCallsign: "W5XYZ", Band: 14, StartTime: targetTime, Duration: time.Hour, NormTxPwr_dBm: 43}
+	err := WriteResultJSON(&buf, AnalyseReports(params, rxReports, nil))
+	if err != nil {
+		t.Fatalf("WriteResultJSON() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		SchemaVersion int    `json:"schema_version"`
+		Target        string `json:"target"`
+		EndTime       string `json:"end_time"`
+		Groups        []struct {
+			RxSign       string  `json:"rx_sign"`
+			Rank         int     `json:"rank"`
+			DbOverMedian int     `json:"db_over_median"`
+			Percentile   float64 `json:"percentile_rank"`
+			Target       struct {
+				TxSign     string `json:"tx_sign"`
+				SnrNorm_dB int    `json:"snr_norm_db"`
+			} `json:"target"`
+			Comparables []struct {
+				TxSign string `json:"tx_sign"`
+			} `json:"comparables"`
+		} `json:"groups"`
+		Aggregate struct {
+			DbMedian *float64 `json:"db_median"`
+			Samples  int      `json:"samples"`
+		} `json:"aggregate"`
+		PercentileRank struct {
+			Mean   *float64 `json:"mean"`
+			Groups int      `json:"groups"`
+		} `json:"percentile_rank"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+	}
+
+	if doc.SchemaVersion != JSONSchemaVersion || doc.Target != "W5XYZ" || doc.EndTime != "2024-12-14T16:30:00Z" {
+		t.Errorf("WriteResultJSON() header = %+v", doc)
+	}
+	if len(doc.Groups) != 1 {
+		t.Fatalf("WriteResultJSON() wrote %d groups, want 1", len(doc.Groups))
+	}
+	group := doc.Groups[0]
+	if group.Rank != 2 || group.DbOverMedian != 0 || group.Target.TxSign != "W5XYZ" || group.Target.SnrNorm_dB != 3 {
+		t.Errorf("WriteResultJSON() group = %+v", group)
+	}
+	if len(group.Comparables) != 2 {
+		t.Errorf("WriteResultJSON() wrote %d comparables, want 2", len(group.Comparables))
+	}
+	if group.Percentile != 50 || doc.PercentileRank.Groups != 1 || doc.PercentileRank.Mean == nil || *doc.PercentileRank.Mean != 50 {
+		t.Errorf("WriteResultJSON() percentile ranks = %v and %+v, want 50", group.Percentile, doc.PercentileRank)
+	}
+	// Relative SNRs are +5 and -5, so the median is 0.
+	if doc.Aggregate.Samples != 2 || doc.Aggregate.DbMedian == nil || *doc.Aggregate.DbMedian != 0 {
+		t.Errorf("WriteResultJSON() aggregate = %+v", doc.Aggregate)
+	}
+}
+
+// TestWriteResultJSON_Confidence tests the bootstrap confidence interval of
+// the aggregate metric.
+func TestWriteResultJSON_Confidence(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign: "W5ABC",
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -20},
+				{TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -22},
+			},
+		},
+		{
+			RxSign: "W5DEF",
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -20},
+				{TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -24},
+			},
+		},
+	}
+	params := AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43}
+
+	for _, tt := range []struct {
+		name       string
+		iterations int
+		wantNull   bool
+	}{
+		{name: "disabled", iterations: 0, wantNull: true},
+		{name: "enabled", iterations: 200, wantNull: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params.BootstrapIterations = tt.iterations
+			var buf bytes.Buffer
+			if err := WriteResultJSON(&buf, AnalyseReports(params, rxReports, nil)); err != nil {
+				t.Fatalf("WriteResultJSON() unexpected error: %v", err)
+			}
+			var doc struct {
+				Aggregate struct {
+					Confidence *struct {
+						Low   float64 `json:"low"`
+						High  float64 `json:"high"`
+						Level float64 `json:"level"`
+					} `json:"confidence"`
+				} `json:"aggregate"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+			}
+			confidence := doc.Aggregate.Confidence
+			if (confidence == nil) != tt.wantNull {
+				t.Fatalf("WriteResultJSON() confidence = %+v, want null: %v", confidence, tt.wantNull)
+			}
+			if confidence != nil && (confidence.Low < 2 || confidence.High > 4 || confidence.Level != ConfidenceLevel) {
+				t.Errorf("WriteResultJSON() confidence = %+v, want within [2, 4] at %v", *confidence, ConfidenceLevel)
+			}
+		})
+	}
+}
+
+// TestWriteResultJSON_Empty tests that the JSON output is well formed with no groups.
+func TestWriteResultJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	params := AnalysisParams{TargetCallsign: "W5XYZ", Band: 14, StartTime: time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC), Duration: time.Hour, NormTxPwr_dBm: 43}
+	err := WriteResultJSON(&buf, AnalyseReports(params, nil, nil))
+	if err != nil {
+		t.Fatalf("WriteResultJSON() unexpected error: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+	}
+	if groups, ok := doc["groups"].([]any); !ok || len(groups) != 0 {
+		t.Errorf("WriteResultJSON() groups = %v, want empty array", doc["groups"])
+	}
+	if aggregate := doc["aggregate"].(map[string]any); aggregate["db_median"] != nil {
+		t.Errorf("WriteResultJSON() db_median = %v, want null", aggregate["db_median"])
+	}
+}
+
+// TestWriteResultCSV tests the rows written by WriteResultCSV.
+func TestWriteResultCSV(t *testing.T) {
+	targetTime := time.Date(2024, 12, 14, 15, 30, 0, 0, time.UTC)
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			Time:        targetTime,
+			TargetIndex: 1,
+			Reports: []ReceptionReport{
+				{TxSign: "G3ABC", Distance_km: 150, Power_dBm: 30, Snr_dB: -5, RxAzimuth: 40},
+				{TxSign: "W5XYZ", Distance_km: 200, Power_dBm: 10, Snr_dB: -30, RxAzimuth: 45},
+				{TxSign: "N0OTH", Distance_km: 225, Power_dBm: 20, Snr_dB: -25, RxAzimuth: 50},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResultCSV(&buf, AnalyseReports(AnalysisParams{NormTxPwr_dBm: 43}, rxReports, nil), ','); err != nil {
+		t.Fatalf("WriteResultCSV() unexpected error: %v", err)
+	}
+
+	want := "rx_sign,time,target_sign,target_snr_db,target_power_dbm,target_snr_norm_db,target_distance_km,target_rx_azimuth_deg," +
+		"tx_sign,snr_db,power_dbm,snr_norm_db,distance_km,rx_azimuth_deg,relative_snr_norm_db\n" +
+		"W5ABC,2024-12-14T15:30:00Z,W5XYZ,-30,10,3,200,45,G3ABC,-5,30,8,150,40,5\n" +
+		"W5ABC,2024-12-14T15:30:00Z,W5XYZ,-30,10,3,200,45,N0OTH,-25,20,-2,225,50,-5\n"
+	if buf.String() != want {
+		t.Errorf("WriteResultCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// TestWriteResultCSV_TSV tests that the separator can be changed for TSV output.
+func TestWriteResultCSV_TSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResultCSV(&buf, AnalyseReports(AnalysisParams{NormTxPwr_dBm: 43}, nil, nil), '\t'); err != nil {
+		t.Fatalf("WriteResultCSV() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "rx_sign\ttime\t") {
+		t.Errorf("WriteResultCSV() header = %q, want tab separated", buf.String())
+	}
+}
+
+// TestWriteResultText tests the text rendering of an AnalysisResult.
+func TestWriteResultText(t *testing.T) {
+	targetTime := time.Date(2024, 12, 14, 15, 30, 0, 0, time.UTC)
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			Time:        targetTime,
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Distance_km: 200, Power_dBm: 10, Snr_dB: -10},
+				{TxSign: "N0OTH", Distance_km: 225, Power_dBm: 20, Snr_dB: -25},
+				{TxSign: "G3ABC", Distance_km: 150, Power_dBm: 30, Snr_dB: -30},
+			},
+		},
+	}
+	filteredOut := []ReceptionReportGroup{{RxSign: "W5DEF", Time: targetTime}}
+
+	var buf bytes.Buffer
+	result := AnalyseReports(AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43}, rxReports, filteredOut)
+	if err := WriteResultText(&buf, result, TextOptions{Verbose: true}); err != nil {
+		t.Fatalf("WriteResultText() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"Reports from W5DEF at 2024-12-14T15:30:00Z filtered out",
+		"Received by W5ABC (distance 200km) at 2024-12-14T15:30:00Z:",
+		"     -->1: Transmitter: W5XYZ",
+		"    1 out of 3 transmitters; Normalised SNR: +23dB, +25dBmedian",
+		"+32.5dBmedian (2 samples)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteResultText() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// TestWriteResultJSON_Breakdowns tests that breakdowns with unbounded and empty bins encode as valid JSON.
+func TestWriteResultJSON_Breakdowns(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 7000},
+				{TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -14, Distance_km: 7100},
+			},
+		},
+	}
+	params := AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43, DistanceBands_km: DefaultDistanceBands_km}
+
+	var buf bytes.Buffer
+	if err := WriteResultJSON(&buf, AnalyseReports(params, rxReports, nil)); err != nil {
+		t.Fatalf("WriteResultJSON() unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Breakdowns []struct {
+			Name string `json:"name"`
+			Bins []struct {
+				High     *float64 `json:"high"`
+				MedianDB *float64 `json:"median_db"`
+				Samples  int      `json:"samples"`
+			} `json:"bins"`
+		} `json:"breakdowns"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteResultJSON() produced invalid JSON: %v", err)
+	}
+	if len(doc.Breakdowns) != 1 || doc.Breakdowns[0].Name != "distance" || len(doc.Breakdowns[0].Bins) != 4 {
+		t.Fatalf("WriteResultJSON() breakdowns = %+v", doc.Breakdowns)
+	}
+	bins := doc.Breakdowns[0].Bins
+	if bins[0].MedianDB != nil || bins[0].High == nil || *bins[0].High != 500 {
+		t.Errorf("WriteResultJSON() first bin = %+v, want empty with high 500", bins[0])
+	}
+	if bins[3].High != nil || bins[3].MedianDB == nil || *bins[3].MedianDB != 4 || bins[3].Samples != 1 {
+		t.Errorf("WriteResultJSON() last bin = %+v, want unbounded with median 4", bins[3])
+	}
+}
+
+// TestWriteBreakdownChart tests the ASCII bar chart of a breakdown.
+func TestWriteBreakdownChart(t *testing.T) {
+	breakdown := Breakdown{
+		Name: "test",
+		Bins: []BreakdownBin{
+			{Label: "a", Median_dB: 4, Samples: 1},
+			{Label: "b", Median_dB: -2, Samples: 1},
+			{Label: "c"},
+		},
+	}
+
+	var buf bytes.Buffer
+	writeBreakdownChart(&buf, breakdown)
+
+	want := "\n" +
+		"    a                                    |#################### +4.0dB\n" +
+		"    b                          ##########|                     -2.0dB\n" +
+		"    c                                    |                     -\n"
+	if buf.String() != want {
+		t.Errorf("writeBreakdownChart() =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
@@ -0,0 +1,291 @@
+package wspr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// NewReceptionReportGroup builds a ReceptionReportGroup (see types.go) from a
+// slice of ReceptionReports. The first report matched by target is the
+// target's. Returns an error if target is not found in reports.
+func NewReceptionReportGroup(reports []ReceptionReport, target CallsignMatcher) (*ReceptionReportGroup, error) {
+	newGroup := new(ReceptionReportGroup)
+	if len(reports) > 0 {
+		newGroup.RxSign = reports[0].RxSign
+		newGroup.Time = reports[0].Time()
+		newGroup.Reports = reports
+		newGroup.TargetIndex = -1
+		for i, report := range newGroup.Reports {
+			if target.Match(report.TxSign) {
+				newGroup.TargetIndex = i
+				break
+			}
+		}
+		if newGroup.TargetIndex == -1 {
+			return nil, fmt.Errorf("target transmitter %s not found in report group for receiver %s at time %s",
+				target, newGroup.RxSign,
+				newGroup.Time.UTC().Format(time.RFC3339))
+		}
+	}
+	return newGroup, nil
+}
+
+// Helper function to calculate the median of an optionally pre-sorted slice.
+// NOTE: If preSorted is false, the input slice will be sorted in place.
+func median[T interface {
+	constraints.Integer | constraints.Float
+}](values []T, preSorted bool) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot calculate median of empty slice")
+	}
+	if !preSorted {
+		slices.Sort(values)
+	}
+	if len(values) == 1 {
+		return float64(values[0]), nil
+	} else if len(values)%2 == 0 {
+		midIndexA := len(values)/2 - 1
+		midIndexB := len(values) / 2
+		return (float64(values[midIndexA]) + float64(values[midIndexB])) / 2, nil
+	} else {
+		midIndex := len(values) / 2
+		return float64(values[midIndex]), nil
+	}
+}
+
+// ProcessRawRxReports groups the raw reports returned by the database query into chunks associated
+// with a particular receiver and time. Within each chunk, the reports are ordered by
+// descending normalised SNR. The normalised SNR is based on a notional transmit power of
+// normTxPower_dBm.
+// The function returns a slice of ReceptionReportGroup structs, with each entry containing
+// the reports for a particular receiver and time. The slice is ordered by time followed by
+// receiver callsign. Duplicate spots are merged with DuplicatesMaxSnr (see GroupRxReports).
+func ProcessRawRxReports(rawRxReports []ReceptionReport, target CallsignMatcher, normTxPwr_dBm int8) ([]ReceptionReportGroup, error) {
+	rxReports, _, err := GroupRxReports(rawRxReports, target, normTxPwr_dBm, DuplicatesMaxSnr)
+	return rxReports, err
+}
+
+// GroupRxReports is ProcessRawRxReports with a choice of how duplicate spots
+// are merged. Reports in a chunk are duplicates if they have the same
+// transmitter callsign (ignoring case and hashing) or are all matched by
+// target, since a receiver can upload the same spot more than once or decode
+// it with several decoders. Also returns the number of reports removed.
+func GroupRxReports(rawRxReports []ReceptionReport, target CallsignMatcher, normTxPwr_dBm int8, duplicates DuplicatePolicy) ([]ReceptionReportGroup, int, error) {
+	return groupRxReports(rawRxReports, target, normTxPwr_dBm, duplicates)
+}
+
+// GroupRxReports, also merging the reports of each of others in the same way
+// as the target's.
+func groupRxReports(rawRxReports []ReceptionReport, target CallsignMatcher, normTxPwr_dBm int8, duplicates DuplicatePolicy, others ...CallsignMatcher) ([]ReceptionReportGroup, int, error) {
+	// rawRxReports is one-dimensional and is ordered by time, followed by receiver callsign.
+	// We need to split it each time the time or receiver field changes and build a
+	// ReceptionReportGroup struct.
+	var rxReports []ReceptionReportGroup
+	removed := 0
+	txKey := duplicateKey(func(report ReceptionReport) string { return report.TxSign }, append([]CallsignMatcher{target}, others...)...)
+	for i, j := 0, 1; j <= len(rawRxReports); j++ {
+		if j == len(rawRxReports) ||
+			rawRxReports[j].TimeStr != rawRxReports[i].TimeStr ||
+			rawRxReports[j].RxSign != rawRxReports[i].RxSign {
+			// The slice rawRxReports[i:j] forms a report group.
+			reportsForGroup := rawRxReports[i:j]
+			// Merge duplicates before sorting so that "first" means first
+			// from the source.
+			reportsForGroup, n := mergeDuplicates(reportsForGroup, txKey, duplicates, normTxPwr_dBm)
+			removed += n
+			// Sort it by descending normalised SNR.
+			slices.SortFunc(reportsForGroup, func(a, b ReceptionReport) int {
+				return cmp.Compare(b.SnrNorm_dB(normTxPwr_dBm), a.SnrNorm_dB(normTxPwr_dBm))
+			})
+			// Build a ReceptionReportGroup struct and append it to rxReports.
+			newGroup, err := NewReceptionReportGroup(reportsForGroup, target)
+			if err != nil {
+				return nil, 0, err
+			}
+			if newGroup == nil || len(newGroup.Reports) == 0 {
+				return nil, 0, fmt.Errorf("generated nil/empty report group. This should not happen")
+			}
+			rxReports = append(rxReports, *newGroup)
+			// Move to the next group.
+			i = j
+		}
+	}
+	return rxReports, removed, nil
+}
+
+// FilterRxReports removes transmitters which are not comparable to the target
+// transmitter from each report group, using DefaultFilterChain. Groups left
+// with no comparable transmitters are returned separately (unmodified) as the
+// second return value.
+func FilterRxReports(rxReports []ReceptionReportGroup, target CallsignMatcher) ([]ReceptionReportGroup, []ReceptionReportGroup, error) {
+	return DefaultFilterChain().Apply(rxReports, target)
+}
+
+// Calculate the median normalised SNR of a report group. The reports in the
+// group must be sorted by descending normalised SNR.
+func medianSnrNorm_dB(reportGroup ReceptionReportGroup, normTxPwr_dBm int8) int8 {
+	reports := reportGroup.Reports
+	if len(reports)%2 == 0 {
+		return (reports[len(reports)/2-1].SnrNorm_dB(normTxPwr_dBm) + reports[len(reports)/2].SnrNorm_dB(normTxPwr_dBm)) / 2
+	}
+	return reports[len(reports)/2].SnrNorm_dB(normTxPwr_dBm)
+}
+
+// Calculate how far the target's normalised SNR is above the median
+// normalised SNR of all the transmitters in the group.
+func targetSnrNormOverMedian_dB(reportGroup ReceptionReportGroup, normTxPwr_dBm int8) int8 {
+	targetSnrNorm := reportGroup.Reports[reportGroup.TargetIndex].SnrNorm_dB(normTxPwr_dBm)
+	return targetSnrNorm - medianSnrNorm_dB(reportGroup, normTxPwr_dBm)
+}
+
+// Calculate the percentage of the non-target transmitters in the group whose
+// normalised SNR is below that of the target, counting ties as half. Unlike
+// the rank, this is comparable between groups of different sizes, and unlike
+// dB differences it is unaffected by the receiver's SNR calibration.
+func targetPercentileRank(reportGroup ReceptionReportGroup, normTxPwr_dBm int8) float64 {
+	if len(reportGroup.Reports) < 2 {
+		return 50
+	}
+	targetSnrNorm := reportGroup.Reports[reportGroup.TargetIndex].SnrNorm_dB(normTxPwr_dBm)
+	var beaten float64
+	for i, report := range reportGroup.Reports {
+		switch snrNorm := report.SnrNorm_dB(normTxPwr_dBm); {
+		case i == reportGroup.TargetIndex:
+		case snrNorm < targetSnrNorm:
+			beaten++
+		case snrNorm == targetSnrNorm:
+			beaten += 0.5
+		}
+	}
+	return 100 * beaten / float64(len(reportGroup.Reports)-1)
+}
+
+// Return the normalised SNRs of all the non-target transmitters in the group
+// relative to that of the target transmitter.
+func relativeSnrNorms_dB(reportGroup ReceptionReportGroup, normTxPwr_dBm int8) []int8 {
+	targetSnrNorm := reportGroup.Reports[reportGroup.TargetIndex].SnrNorm_dB(normTxPwr_dBm)
+	relativeSnrNorms := make([]int8, 0, len(reportGroup.Reports)-1)
+	for i, report := range reportGroup.Reports {
+		if i != reportGroup.TargetIndex {
+			relativeSnrNorms = append(relativeSnrNorms, report.SnrNorm_dB(normTxPwr_dBm)-targetSnrNorm)
+		}
+	}
+	return relativeSnrNorms
+}
+
+// ErrNoReports is returned (wrapped) by RunAnalysis if the source has no
+// reports for the target in the requested time range.
+var ErrNoReports = errors.New("no reception reports found")
+
+// AnalyseReports calculates the statistics for each report group and the
+// aggregate metric.
+// filteredOut lists the groups which were dropped during filtering; it is
+// only recorded in the result. This function has no side effects.
+func AnalyseReports(params AnalysisParams, rxReports []ReceptionReportGroup, filteredOut []ReceptionReportGroup) *AnalysisResult {
+	result := &AnalysisResult{
+		AnalysisParams: params,
+		Groups:         make([]GroupResult, 0, len(rxReports)),
+		FilteredOut:    filteredOut,
+	}
+	for _, reportGroup := range rxReports {
+		groupResult := GroupResult{
+			ReceptionReportGroup: reportGroup,
+			Rank:                 reportGroup.TargetIndex + 1,
+			TargetSnrNorm_dB:     reportGroup.Reports[reportGroup.TargetIndex].SnrNorm_dB(params.NormTxPwr_dBm),
+			SnrNormOverMedian_dB: targetSnrNormOverMedian_dB(reportGroup, params.NormTxPwr_dBm),
+			PercentileRank:       targetPercentileRank(reportGroup, params.NormTxPwr_dBm),
+			RelativeSnrNorms_dB:  relativeSnrNorms_dB(reportGroup, params.NormTxPwr_dBm),
+		}
+		result.Groups = append(result.Groups, groupResult)
+		result.PercentileRank.Mean += groupResult.PercentileRank
+	}
+	if len(result.Groups) > 0 {
+		result.PercentileRank.Groups = len(result.Groups)
+		result.PercentileRank.Mean /= float64(len(result.Groups))
+	}
+	result.StrategyAggregates = strategyAggregates(result.Groups)
+	result.Aggregate = aggregateMetric(result.Groups, params.Aggregation)
+	if result.Aggregate.Valid() {
+		result.Aggregate.Confidence = bootstrapAggregate(result.Groups, params.Aggregation, params.BootstrapIterations, params.BootstrapSeed)
+	}
+	if params.AzimuthSectorWidth_deg > 0 {
+		result.Breakdowns = append(result.Breakdowns, AzimuthBreakdown(result.Groups, params.AzimuthSectorWidth_deg))
+	}
+	if len(params.DistanceBands_km) > 0 {
+		result.Breakdowns = append(result.Breakdowns, DistanceBreakdown(result.Groups, params.DistanceBands_km))
+	}
+	if params.HourlyProfile {
+		if params.SolarTime {
+			result.Breakdowns = append(result.Breakdowns, HourlyBreakdown(result.Groups, SolarTimeOffset(params.TargetLongitude_deg), "solar time"))
+		} else {
+			result.Breakdowns = append(result.Breakdowns, HourlyBreakdown(result.Groups, 0, "UTC"))
+		}
+	}
+	return result
+}
+
+// RunAnalysis orchestrates the query, filtering and statistics. This is the
+// function called by main.go. The raw reception reports are obtained from
+// source. The result can be written out with WriteResult.
+func RunAnalysis(source ReportSource, params AnalysisParams) (*AnalysisResult, error) {
+	// Fetch the raw reception reports from the data source.
+	rawRxReports, err := source.FetchReports(params.Target(), params.Band, params.StartTime, params.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reception reports (%w)", err)
+	}
+	if len(rawRxReports) == 0 {
+		return nil, fmt.Errorf("%w for %s on band %d in the specified time range", ErrNoReports, params.TargetCallsign, params.Band)
+	}
+	// Process the raw reception reports into structured groups.
+	rxReports, duplicatesRemoved, err := GroupRxReports(rawRxReports, params.Target(), params.NormTxPwr_dBm, params.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	// Note where the target was and keep only the chosen location.
+	targetLocators := TargetLocators(rxReports)
+	if params.TargetLocator != "" {
+		rxReports = restrictToTargetLocator(rxReports, params.TargetLocator)
+		duplicatesRemoved = duplicatesRemovedFrom(rawRxReports, rxReports)
+		if len(rxReports) == 0 {
+			return nil, fmt.Errorf("%w for %s from locator %s on band %d in the specified time range", ErrNoReports, params.TargetCallsign, params.TargetLocator, params.Band)
+		}
+	}
+	// Remove any excluded receivers and transmitters.
+	var excluded Exclusions
+	if params.Exclusions != nil {
+		rxReports, excluded = params.Exclusions.Apply(rxReports)
+	}
+	// Filter the reception reports to remove non-comparable transmitters.
+	filter := DefaultFilterChain()
+	if params.Filter != nil {
+		filter = *params.Filter
+	}
+	rxReports, filteredOut, err := filter.Apply(rxReports, params.Target())
+	if err != nil {
+		return nil, err
+	}
+	// Look for receivers with inconsistent reports.
+	var outliers []ReceiverSpread
+	if params.OutlierMode != OutliersOff {
+		threshold := params.OutlierThreshold
+		if threshold == 0 {
+			threshold = DefaultOutlierThreshold
+		}
+		outliers = DetectOutlierReceivers(rxReports, params.NormTxPwr_dBm, threshold)
+		if params.OutlierMode == OutliersDrop {
+			rxReports = dropReceivers(rxReports, outliers)
+		}
+	}
+	// Calculate the stats.
+	result := AnalyseReports(params, rxReports, filteredOut)
+	result.DuplicatesRemoved = duplicatesRemoved
+	result.TargetLocators = targetLocators
+	result.Excluded = excluded
+	result.Outliers = outliers
+	return result, nil
+}
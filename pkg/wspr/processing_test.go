@@ -1,11 +1,12 @@
-package wspranalysis
+package wspr
 
 import (
+	"slices"
 	"testing"
 	"time"
 )
 
-// TestNewReceptionReportGroup tests the newReceptionReportGroup function.
+// TestNewReceptionReportGroup tests the NewReceptionReportGroup function.
 func TestNewReceptionReportGroup(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -61,23 +62,23 @@ func TestNewReceptionReportGroup(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := newReceptionReportGroup(tt.reports, tt.targetCallsign)
+			result, err := NewReceptionReportGroup(tt.reports, ExactCallsign(tt.targetCallsign))
 
 			if (err != nil) != tt.wantError {
-				t.Errorf("newReceptionReportGroup() error = %v, wantError %v", err, tt.wantError)
+				t.Errorf("NewReceptionReportGroup() error = %v, wantError %v", err, tt.wantError)
 			}
 
 			if !tt.wantError && result != nil {
 				if result.TargetIndex != tt.wantTargetIndex {
-					t.Errorf("newReceptionReportGroup() TargetIndex = %d, want %d", result.TargetIndex, tt.wantTargetIndex)
+					t.Errorf("NewReceptionReportGroup() TargetIndex = %d, want %d", result.TargetIndex, tt.wantTargetIndex)
 				}
 				// Only check RxSign and length if we have reports
 				if len(tt.reports) > 0 {
 					if result.RxSign != tt.wantRxSign {
-						t.Errorf("newReceptionReportGroup() RxSign = %s, want %s", result.RxSign, tt.wantRxSign)
+						t.Errorf("NewReceptionReportGroup() RxSign = %s, want %s", result.RxSign, tt.wantRxSign)
 					}
 					if len(result.Reports) != tt.wantReportsLength {
-						t.Errorf("newReceptionReportGroup() Reports length = %d, want %d", len(result.Reports), tt.wantReportsLength)
+						t.Errorf("NewReceptionReportGroup() Reports length = %d, want %d", len(result.Reports), tt.wantReportsLength)
 					}
 				}
 			}
@@ -172,7 +173,7 @@ func TestMedian_FloatValues(t *testing.T) {
 	}
 }
 
-// TestProcessRawRxReports tests the processRawRxReports function.
+// TestProcessRawRxReports tests the ProcessRawRxReports function.
 func TestProcessRawRxReports(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -224,26 +225,26 @@ func TestProcessRawRxReports(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := processRawRxReports(tt.rawReports, tt.targetCallsign, tt.normTxPwr_dBm)
+			result, err := ProcessRawRxReports(tt.rawReports, ExactCallsign(tt.targetCallsign), tt.normTxPwr_dBm)
 
 			if (err != nil) != tt.wantError {
-				t.Errorf("processRawRxReports() error = %v, wantError %v", err, tt.wantError)
+				t.Errorf("ProcessRawRxReports() error = %v, wantError %v", err, tt.wantError)
 			}
 
 			if !tt.wantError {
 				if len(result) != tt.wantGroupsCount {
-					t.Errorf("processRawRxReports() returned %d groups, want %d", len(result), tt.wantGroupsCount)
+					t.Errorf("ProcessRawRxReports() returned %d groups, want %d", len(result), tt.wantGroupsCount)
 				}
 
 				if len(result) > 0 && len(result[0].Reports) != tt.wantFirstGroupSize {
-					t.Errorf("processRawRxReports() first group has %d reports, want %d", len(result[0].Reports), tt.wantFirstGroupSize)
+					t.Errorf("ProcessRawRxReports() first group has %d reports, want %d", len(result[0].Reports), tt.wantFirstGroupSize)
 				}
 			}
 		})
 	}
 }
 
-// TestProcessRawRxReports_SortedBySnr tests that processRawRxReports sorts reports by normalized SNR.
+// TestProcessRawRxReports_SortedBySnr tests that ProcessRawRxReports sorts reports by normalized SNR.
 func TestProcessRawRxReports_SortedBySnr(t *testing.T) {
 	rawReports := []ReceptionReport{
 		{TimeStr: "2024-12-14 15:30:45", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -5},
@@ -251,14 +252,14 @@ func TestProcessRawRxReports_SortedBySnr(t *testing.T) {
 		{TimeStr: "2024-12-14 15:30:45", RxSign: "W5ABC", TxSign: "G3ABC", Power_dBm: 30, Snr_dB: -20},
 	}
 
-	result, err := processRawRxReports(rawReports, "W5XYZ", int8(43))
+	result, err := ProcessRawRxReports(rawReports, ExactCallsign("W5XYZ"), int8(43))
 
 	if err != nil {
-		t.Fatalf("processRawRxReports() unexpected error: %v", err)
+		t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
 	}
 
 	if len(result) != 1 {
-		t.Fatalf("processRawRxReports() returned %d groups, want 1", len(result))
+		t.Fatalf("ProcessRawRxReports() returned %d groups, want 1", len(result))
 	}
 
 	// Check that reports are sorted by descending normalized SNR
@@ -266,12 +267,37 @@ func TestProcessRawRxReports_SortedBySnr(t *testing.T) {
 		snr1 := result[0].Reports[i].SnrNorm_dB(43)
 		snr2 := result[0].Reports[i+1].SnrNorm_dB(43)
 		if snr1 < snr2 {
-			t.Errorf("processRawRxReports() reports not sorted by descending SNR: %d >= %d", snr1, snr2)
+			t.Errorf("ProcessRawRxReports() reports not sorted by descending SNR: %d >= %d", snr1, snr2)
 		}
 	}
 }
 
-// TestFilterRxReports tests the filterRxReports function.
+// TestProcessRawRxReports_CompoundTarget tests that compound and hashed forms of the target are found and not compared with each other.
+func TestProcessRawRxReports_CompoundTarget(t *testing.T) {
+	rawReports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -5},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "<PA/W5XYZ>", Power_dBm: 10, Snr_dB: -12},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "PA/W5XYZ", Power_dBm: 10, Snr_dB: -10},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -5},
+	}
+
+	if _, err := ProcessRawRxReports(slices.Clone(rawReports), ExactCallsign("W5XYZ"), 43); err == nil {
+		t.Errorf("ProcessRawRxReports() expected error without compound matching, got nil")
+	}
+	result, err := ProcessRawRxReports(rawReports[:3], CallsignMatcher{Callsign: "W5XYZ", Compound: true}, 43)
+
+	if err != nil {
+		t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
+	}
+	if len(result) != 1 || len(result[0].Reports) != 2 {
+		t.Fatalf("ProcessRawRxReports() = %+v, want one group of 2 reports", result)
+	}
+	if target := result[0].Reports[result[0].TargetIndex]; target.TxSign != "PA/W5XYZ" || target.Snr_dB != -10 {
+		t.Errorf("ProcessRawRxReports() target report = %+v, want the stronger PA/W5XYZ", target)
+	}
+}
+
+// TestFilterRxReports tests the FilterRxReports function.
 func TestFilterRxReports(t *testing.T) {
 	targetTime := time.Date(2024, 12, 14, 15, 30, 45, 0, time.UTC)
 
@@ -321,14 +347,14 @@ func TestFilterRxReports(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := filterRxReports(tt.rxReports, tt.targetCallsign)
+			result, _, err := FilterRxReports(tt.rxReports, ExactCallsign(tt.targetCallsign))
 
 			if (err != nil) != tt.wantError {
-				t.Errorf("filterRxReports() error = %v, wantError %v", err, tt.wantError)
+				t.Errorf("FilterRxReports() error = %v, wantError %v", err, tt.wantError)
 			}
 
 			if !tt.wantError && len(result) != tt.wantGroupsCount {
-				t.Errorf("filterRxReports() returned %d groups, want %d", len(result), tt.wantGroupsCount)
+				t.Errorf("FilterRxReports() returned %d groups, want %d", len(result), tt.wantGroupsCount)
 			}
 		})
 	}
@@ -351,18 +377,18 @@ func TestFilterRxReports_DistanceRangeCalculation(t *testing.T) {
 		},
 	}
 
-	result, err := filterRxReports(rxReports, "W5XYZ")
+	result, _, err := FilterRxReports(rxReports, ExactCallsign("W5XYZ"))
 
 	if err != nil {
-		t.Fatalf("filterRxReports() unexpected error: %v", err)
+		t.Fatalf("FilterRxReports() unexpected error: %v", err)
 	}
 
 	if len(result) != 1 {
-		t.Errorf("filterRxReports() returned %d groups, want 1", len(result))
+		t.Errorf("FilterRxReports() returned %d groups, want 1", len(result))
 	}
 
 	if len(result) > 0 && len(result[0].Reports) != 3 {
-		t.Errorf("filterRxReports() filtered group has %d reports, want 3", len(result[0].Reports))
+		t.Errorf("FilterRxReports() filtered group has %d reports, want 3", len(result[0].Reports))
 	}
 }
 
@@ -441,14 +467,14 @@ func TestProcessRawRxReports_MultipleGroups(t *testing.T) {
 		{TimeStr: "2024-12-14 15:31:45", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 18, Snr_dB: -14},
 	}
 
-	result, err := processRawRxReports(rawReports, "W5XYZ", 43)
+	result, err := ProcessRawRxReports(rawReports, ExactCallsign("W5XYZ"), 43)
 
 	if err != nil {
-		t.Fatalf("processRawRxReports() unexpected error: %v", err)
+		t.Fatalf("ProcessRawRxReports() unexpected error: %v", err)
 	}
 
 	if len(result) != 3 {
-		t.Errorf("processRawRxReports() returned %d groups, want 3", len(result))
+		t.Errorf("ProcessRawRxReports() returned %d groups, want 3", len(result))
 	}
 }
 
@@ -470,10 +496,10 @@ func TestFilterRxReports_MinimumDistance(t *testing.T) {
 		},
 	}
 
-	result, err := filterRxReports(rxReports, "W5XYZ")
+	result, _, err := FilterRxReports(rxReports, ExactCallsign("W5XYZ"))
 
 	if err != nil {
-		t.Fatalf("filterRxReports() unexpected error: %v", err)
+		t.Fatalf("FilterRxReports() unexpected error: %v", err)
 	}
 
 	// N0OTH should be included (15 >= 20*0.75 = 15)
@@ -487,7 +513,136 @@ func TestFilterRxReports_MinimumDistance(t *testing.T) {
 			}
 		}
 		if !hasN0OTH {
-			t.Errorf("filterRxReports() should have included transmitter at 15km (75%% of target distance)")
+			t.Errorf("FilterRxReports() should have included transmitter at 15km (75%% of target distance)")
 		}
 	}
 }
+
+// TestAnalyseReports tests the per-group and aggregate statistics.
+func TestAnalyseReports(t *testing.T) {
+	targetTime := time.Date(2024, 12, 14, 15, 30, 45, 0, time.UTC)
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			Time:        targetTime,
+			TargetIndex: 1,
+			Reports: []ReceptionReport{
+				{TxSign: "G3ABC", Power_dBm: 30, Snr_dB: -5},  // Normalised: +8
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -30}, // Normalised: +3
+				{TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -27}, // Normalised: -4
+			},
+		},
+		{
+			RxSign:      "W5DEF",
+			Time:        targetTime,
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -20}, // Normalised: +13
+				{TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -20}, // Normalised: +3
+			},
+		},
+	}
+	filteredOut := []ReceptionReportGroup{{RxSign: "K1AAA", Time: targetTime}}
+
+	result := AnalyseReports(AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43}, rxReports, filteredOut)
+
+	if len(result.Groups) != 2 || len(result.FilteredOut) != 1 {
+		t.Fatalf("AnalyseReports() returned %d groups and %d filtered out, want 2 and 1", len(result.Groups), len(result.FilteredOut))
+	}
+	first := result.Groups[0]
+	if first.Rank != 2 || first.TargetSnrNorm_dB != 3 || first.SnrNormOverMedian_dB != 0 {
+		t.Errorf("AnalyseReports() first group = %+v", first)
+	}
+	if len(first.RelativeSnrNorms_dB) != 2 || first.RelativeSnrNorms_dB[0] != 5 || first.RelativeSnrNorms_dB[1] != -7 {
+		t.Errorf("AnalyseReports() first group relative SNRs = %v, want [5 -7]", first.RelativeSnrNorms_dB)
+	}
+	// The target beat one of two in the first group and all of the second.
+	if first.PercentileRank != 50 || result.PercentileRank.Groups != 2 || result.PercentileRank.Mean != 75 {
+		t.Errorf("AnalyseReports() percentile ranks = %v and %+v, want 50 and a mean of 75", first.PercentileRank, result.PercentileRank)
+	}
+	// Relative SNRs are +5, -7 and -10, so the median is -7.
+	if result.Aggregate.Samples != 3 || !result.Aggregate.Valid() || result.Aggregate.DbMedian != 7 {
+		t.Errorf("AnalyseReports() aggregate = %+v, want 7 from 3 samples", result.Aggregate)
+	}
+}
+
+// TestTargetPercentileRank tests the percentile rank, including ties.
+func TestTargetPercentileRank(t *testing.T) {
+	tests := []struct {
+		name string
+		snrs []int8 // Target first.
+		want float64
+	}{
+		{name: "best", snrs: []int8{-5, -10, -15}, want: 100},
+		{name: "worst", snrs: []int8{-20, -10, -15}, want: 0},
+		{name: "middle", snrs: []int8{-12, -10, -15}, want: 50},
+		{name: "tie counts half", snrs: []int8{-10, -10, -15, -5, -20}, want: 62.5},
+		{name: "alone", snrs: []int8{-10}, want: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := ReceptionReportGroup{TargetIndex: 0}
+			for _, snr := range tt.snrs {
+				group.Reports = append(group.Reports, ReceptionReport{Power_dBm: 43, Snr_dB: snr})
+			}
+			if got := targetPercentileRank(group, 43); got != tt.want {
+				t.Errorf("targetPercentileRank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAnalyseReports_Bootstrap tests that a confidence interval is attached to
+// the aggregate metric only when bootstrapping is enabled.
+func TestAnalyseReports_Bootstrap(t *testing.T) {
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign: "W5ABC",
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -20},
+				{TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -24},
+				{TxSign: "G3ABC", Power_dBm: 10, Snr_dB: -26},
+			},
+		},
+	}
+	params := AnalysisParams{TargetCallsign: "W5XYZ", NormTxPwr_dBm: 43}
+
+	if result := AnalyseReports(params, rxReports, nil); result.Aggregate.Confidence.Valid {
+		t.Errorf("AnalyseReports() without bootstrapping has confidence interval %+v", result.Aggregate.Confidence)
+	}
+	params.BootstrapIterations = 100
+	result := AnalyseReports(params, rxReports, nil)
+	// With a single group every resample gives the same metric.
+	if ci := result.Aggregate.Confidence; !ci.Valid || ci.Low != 5 || ci.High != 5 {
+		t.Errorf("AnalyseReports() confidence interval = %+v, want [5, 5]", ci)
+	}
+}
+
+// TestFilterRxReports_FilteredOut tests that dropped groups are returned separately.
+func TestFilterRxReports_FilteredOut(t *testing.T) {
+	targetTime := time.Date(2024, 12, 14, 15, 30, 45, 0, time.UTC)
+	rxReports := []ReceptionReportGroup{
+		{
+			RxSign:      "W5ABC",
+			Time:        targetTime,
+			TargetIndex: 0,
+			Reports: []ReceptionReport{
+				{TxSign: "W5XYZ", Distance_km: 200},
+				{TxSign: "N0OTH", Distance_km: 500},
+			},
+		},
+	}
+
+	result, filteredOut, err := FilterRxReports(rxReports, ExactCallsign("W5XYZ"))
+
+	if err != nil {
+		t.Fatalf("FilterRxReports() unexpected error: %v", err)
+	}
+	if len(result) != 0 || len(filteredOut) != 1 {
+		t.Fatalf("FilterRxReports() returned %d groups and %d filtered out, want 0 and 1", len(result), len(filteredOut))
+	}
+	if len(filteredOut[0].Reports) != 2 {
+		t.Errorf("FilterRxReports() filtered out group has %d reports, want the original 2", len(filteredOut[0].Reports))
+	}
+}
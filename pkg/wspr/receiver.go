@@ -0,0 +1,271 @@
+// This file contains the receiver-perspective analysis, which assesses a
+// receiving station by comparing the SNRs it reports for each transmitter with
+// those reported by other receivers for the same transmission.
+
+package wspr
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Statistics for a single TransmissionReportGroup, describing how the target
+// receiver compares with the others which heard the transmission.
+type RxGroupResult struct {
+	TransmissionReportGroup
+	// 1-based position of the target when ordered by descending SNR.
+	Rank             int
+	TargetSnr_dB     int8
+	SnrOverMedian_dB int8
+	// Percentage of the other receivers which the target beat, counting ties
+	// as half (0-100).
+	PercentileRank float64
+	// SNRs reported by the non-target receivers relative to the target.
+	RelativeSnrs_dB []int8
+}
+
+// The complete result of a receiver-perspective analysis. The target receiver
+// is AnalysisParams.TargetCallsign. The aggregate metric is positive if the
+// target tends to report higher SNRs than the other receivers.
+type RxAnalysisResult struct {
+	AnalysisParams
+	Groups []RxGroupResult
+	// Groups which were dropped for lack of comparable receivers.
+	FilteredOut []TransmissionReportGroup
+	// Number of duplicate spots merged (see AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+	Aggregate         AggregateMetric
+	PercentileRank    PercentileRankMetric
+}
+
+// NewTransmissionReportGroup builds a TransmissionReportGroup from a slice of
+// ReceptionReports. The first report matched by target is the target's.
+// Returns an error if target is not found in reports.
+func NewTransmissionReportGroup(reports []ReceptionReport, target CallsignMatcher) (*TransmissionReportGroup, error) {
+	newGroup := new(TransmissionReportGroup)
+	if len(reports) > 0 {
+		newGroup.TxSign = reports[0].TxSign
+		newGroup.Time = reports[0].Time()
+		newGroup.Reports = reports
+		newGroup.TargetIndex = -1
+		for i, report := range newGroup.Reports {
+			if target.Match(report.RxSign) {
+				newGroup.TargetIndex = i
+				break
+			}
+		}
+		if newGroup.TargetIndex == -1 {
+			return nil, fmt.Errorf("target receiver %s not found in report group for transmitter %s at time %s",
+				target, newGroup.TxSign,
+				newGroup.Time.UTC().Format(time.RFC3339))
+		}
+	}
+	return newGroup, nil
+}
+
+// ProcessRawTxReports groups the raw reports returned by an RxReportSource
+// into chunks associated with a particular transmitter and time. Within each
+// chunk, the reports are ordered by descending SNR. The slice is ordered by
+// time followed by transmitter callsign. Duplicate spots are merged with
+// DuplicatesMaxSnr (see GroupTxReports).
+func ProcessRawTxReports(rawTxReports []ReceptionReport, target CallsignMatcher) ([]TransmissionReportGroup, error) {
+	txReports, _, err := GroupTxReports(rawTxReports, target, DuplicatesMaxSnr)
+	return txReports, err
+}
+
+// GroupTxReports is ProcessRawTxReports with a choice of how duplicate spots
+// are merged, as in GroupRxReports but with the receiver callsigns. Also
+// returns the number of reports removed.
+func GroupTxReports(rawTxReports []ReceptionReport, target CallsignMatcher, duplicates DuplicatePolicy) ([]TransmissionReportGroup, int, error) {
+	// rawTxReports is ordered by time, followed by transmitter callsign, so
+	// split it each time either of them changes.
+	var txReports []TransmissionReportGroup
+	removed := 0
+	rxKey := duplicateKey(func(report ReceptionReport) string { return report.RxSign }, target)
+	for i, j := 0, 1; j <= len(rawTxReports); j++ {
+		if j == len(rawTxReports) ||
+			rawTxReports[j].TimeStr != rawTxReports[i].TimeStr ||
+			rawTxReports[j].TxSign != rawTxReports[i].TxSign {
+			reportsForGroup, n := mergeDuplicates(rawTxReports[i:j], rxKey, duplicates, 0)
+			removed += n
+			// All the reports are of the same transmission, so there is no
+			// need to normalise the SNRs.
+			slices.SortFunc(reportsForGroup, func(a, b ReceptionReport) int {
+				return cmp.Compare(b.Snr_dB, a.Snr_dB)
+			})
+			newGroup, err := NewTransmissionReportGroup(reportsForGroup, target)
+			if err != nil {
+				return nil, 0, err
+			}
+			if newGroup == nil || len(newGroup.Reports) == 0 {
+				return nil, 0, fmt.Errorf("generated nil/empty report group. This should not happen")
+			}
+			txReports = append(txReports, *newGroup)
+			i = j
+		}
+	}
+	return txReports, removed, nil
+}
+
+// ApplyTx removes receivers which are not comparable to the target receiver
+// from each transmission group. The rules see the reports exactly as in
+// Apply, so DistanceRule compares the distances of the receivers from the
+// transmitter. Any PowerRule is left out, since every report in a group is
+// of the same transmitter. Groups left with too few comparable receivers are
+// returned separately (unmodified) as the second return value.
+func (c FilterChain) ApplyTx(txReports []TransmissionReportGroup, target CallsignMatcher) ([]TransmissionReportGroup, []TransmissionReportGroup, error) {
+	c.Rules = slices.DeleteFunc(slices.Clone(c.Rules), func(rule ComparabilityRule) bool {
+		_, isPowerRule := rule.(PowerRule)
+		return isPowerRule
+	})
+	var filteredReports, filteredOut []TransmissionReportGroup
+	for _, reportGroup := range txReports {
+		targetReport := reportGroup.Reports[reportGroup.TargetIndex]
+		filteredListForGroup := make([]ReceptionReport, 0, len(reportGroup.Reports))
+		for _, report := range reportGroup.Reports {
+			if c.comparable(targetReport, report) {
+				filteredListForGroup = append(filteredListForGroup, report)
+			}
+		}
+		newReportGroup, err := NewTransmissionReportGroup(filteredListForGroup, target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error building filtered report group (%w)", err)
+		}
+		if newReportGroup == nil || len(newReportGroup.Reports)-1 < max(c.MinComparables, 1) {
+			filteredOut = append(filteredOut, reportGroup)
+		} else {
+			filteredReports = append(filteredReports, *newReportGroup)
+		}
+	}
+	return filteredReports, filteredOut, nil
+}
+
+// View a transmission group as a ReceptionReportGroup so that the statistics
+// functions written for transmitter analysis can be reused. The transmitter
+// takes the place of the receiver.
+func (g TransmissionReportGroup) mirrored() ReceptionReportGroup {
+	return ReceptionReportGroup{RxSign: g.TxSign, Time: g.Time, Reports: g.Reports, TargetIndex: g.TargetIndex}
+}
+
+// AnalyseTxReports calculates the statistics for each transmission group and
+// the aggregate metric. It is the receiver-perspective counterpart of
+// AnalyseReports; the AggregatePerReceiver strategy weights each transmitter
+// equally instead.
+func AnalyseTxReports(params AnalysisParams, txReports []TransmissionReportGroup, filteredOut []TransmissionReportGroup) *RxAnalysisResult {
+	result := &RxAnalysisResult{
+		AnalysisParams: params,
+		Groups:         make([]RxGroupResult, 0, len(txReports)),
+		FilteredOut:    filteredOut,
+	}
+	mirroredGroups := make([]GroupResult, 0, len(txReports))
+	for _, reportGroup := range txReports {
+		// Normalising to the transmitter's own power leaves the SNRs
+		// unchanged, since every report is of the same transmission.
+		group := reportGroup.mirrored()
+		txPwr_dBm := group.Reports[group.TargetIndex].Power_dBm
+		groupResult := RxGroupResult{
+			TransmissionReportGroup: reportGroup,
+			Rank:                    reportGroup.TargetIndex + 1,
+			TargetSnr_dB:            group.Reports[group.TargetIndex].Snr_dB,
+			SnrOverMedian_dB:        targetSnrNormOverMedian_dB(group, txPwr_dBm),
+			PercentileRank:          targetPercentileRank(group, txPwr_dBm),
+			RelativeSnrs_dB:         relativeSnrNorms_dB(group, txPwr_dBm),
+		}
+		result.Groups = append(result.Groups, groupResult)
+		result.PercentileRank.Mean += groupResult.PercentileRank
+		mirroredGroups = append(mirroredGroups, GroupResult{ReceptionReportGroup: group, RelativeSnrNorms_dB: groupResult.RelativeSnrs_dB})
+	}
+	if len(result.Groups) > 0 {
+		result.PercentileRank.Groups = len(result.Groups)
+		result.PercentileRank.Mean /= float64(len(result.Groups))
+	}
+	result.Aggregate = aggregateMetric(mirroredGroups, params.Aggregation)
+	if result.Aggregate.Valid() {
+		result.Aggregate.Confidence = bootstrapAggregate(mirroredGroups, params.Aggregation, params.BootstrapIterations, params.BootstrapSeed)
+	}
+	return result
+}
+
+// RunRxAnalysis assesses the receiver params.TargetCallsign by comparing the
+// SNRs it reported for each transmission with those reported by comparable
+// receivers (by default, those at a similar distance from the transmitter).
+// The exclusion lists, outlier detection and breakdowns in params are not
+// used.
+func RunRxAnalysis(source RxReportSource, params AnalysisParams) (*RxAnalysisResult, error) {
+	rawTxReports, err := source.FetchRxReports(params.Target(), params.Band, params.StartTime, params.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reception reports (%w)", err)
+	}
+	if len(rawTxReports) == 0 {
+		return nil, fmt.Errorf("%w by %s on band %d in the specified time range", ErrNoReports, params.TargetCallsign, params.Band)
+	}
+	txReports, duplicatesRemoved, err := GroupTxReports(rawTxReports, params.Target(), params.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	if params.TargetLocator != "" {
+		// The target is the receiver, so its locator is the receiver's.
+		var kept []TransmissionReportGroup
+		for _, reportGroup := range txReports {
+			if MatchLocator(reportGroup.Reports[reportGroup.TargetIndex].RxLoc, params.TargetLocator) {
+				kept = append(kept, reportGroup)
+			}
+		}
+		txReports = kept
+		duplicatesRemoved = txDuplicatesRemovedFrom(rawTxReports, txReports)
+	}
+	filter := DefaultFilterChain()
+	if params.Filter != nil {
+		filter = *params.Filter
+	}
+	txReports, filteredOut, err := filter.ApplyTx(txReports, params.Target())
+	if err != nil {
+		return nil, err
+	}
+	result := AnalyseTxReports(params, txReports, filteredOut)
+	result.DuplicatesRemoved = duplicatesRemoved
+	return result, nil
+}
+
+// WriteRxResultText writes the result of a receiver-perspective analysis
+// nicely formatted for the console. If verbose is set, every report is listed.
+func WriteRxResultText(w io.Writer, result *RxAnalysisResult, verbose bool) error {
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	for _, reportGroup := range result.FilteredOut {
+		fmt.Fprintf(w, "Transmission from %s at %s filtered out due to insufficient comparable receivers\n", reportGroup.TxSign, reportGroup.Time.UTC().Format(time.RFC3339))
+	}
+	for _, group := range result.Groups {
+		fmt.Fprintf(w, "Transmission from %s (distance %dkm) at %s:\n", group.TxSign, group.Reports[group.TargetIndex].Distance_km,
+			group.Time.UTC().Format(time.RFC3339))
+		if verbose {
+			for i, report := range group.Reports {
+				if i == group.TargetIndex {
+					fmt.Fprintf(w, "     -->")
+				} else {
+					fmt.Fprintf(w, "        ")
+				}
+				fmt.Fprintf(w, "%d: Receiver: %s, Distance: %dkm, Azimuth: %dº, SNR: %+ddB\n", i+1,
+					report.RxSign, report.Distance_km, report.TxAzimuth, report.Snr_dB)
+			}
+		}
+		fmt.Fprintf(w, "    %d out of %d receivers; SNR: %+ddB, %+ddBmedian, percentile rank %.0f%%\n", group.Rank, len(group.Reports),
+			group.TargetSnr_dB, group.SnrOverMedian_dB, group.PercentileRank)
+	}
+	fmt.Fprintf(w, "\nOffset from median of relative SNR reported by all other receivers: ")
+	if result.Aggregate.Valid() {
+		fmt.Fprintf(w, "%+.1fdBmedian", result.Aggregate.DbMedian)
+		if result.BootstrapIterations > 0 {
+			fmt.Fprintf(w, ", %v (bootstrap, %d iterations)", result.Aggregate.Confidence, result.BootstrapIterations)
+		}
+		fmt.Fprintf(w, " (%d samples)\n", result.Aggregate.Samples)
+	} else {
+		fmt.Fprintf(w, "not enough samples\n")
+	}
+	if result.PercentileRank.Groups > 0 {
+		fmt.Fprintf(w, "Mean percentile rank among comparable receivers: %.1f%% (%d groups)\n", result.PercentileRank.Mean, result.PercentileRank.Groups)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package wspr
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRxSource is an RxReportSource which returns canned reports, for testing.
+type fakeRxSource struct {
+	reports []ReceptionReport
+	err     error
+}
+
+func (f *fakeRxSource) FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	return f.reports, f.err
+}
+
+// Reports of two transmissions heard by the target receiver W5ABC and others,
+// ordered by time and transmitter as an RxReportSource returns them.
+var testRxReports = []ReceptionReport{
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -12, Distance_km: 1000},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -8, Distance_km: 1100},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5GHI", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -20, Distance_km: 950},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "VK2FAR", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: 5, Distance_km: 9000},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 37, Snr_dB: 0, Distance_km: 400},
+	{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "W5XYZ", Power_dBm: 37, Snr_dB: -5, Distance_km: 420},
+	{TimeStr: "2024-12-14 15:32:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -10, Distance_km: 1000},
+}
+
+// TestProcessRawTxReports tests grouping by time and transmitter.
+func TestProcessRawTxReports(t *testing.T) {
+	raw := append([]ReceptionReport(nil), testRxReports...)
+
+	groups, err := ProcessRawTxReports(raw, ExactCallsign("W5ABC"))
+
+	if err != nil {
+		t.Fatalf("ProcessRawTxReports() unexpected error: %v", err)
+	}
+	want := []struct {
+		txSign      string
+		reports     int
+		targetIndex int
+	}{
+		{"N0OTH", 4, 2},
+		{"W5XYZ", 2, 0},
+		{"N0OTH", 1, 0},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("ProcessRawTxReports() returned %d groups, want %d", len(groups), len(want))
+	}
+	for i, w := range want {
+		if groups[i].TxSign != w.txSign || len(groups[i].Reports) != w.reports || groups[i].TargetIndex != w.targetIndex {
+			t.Errorf("group %d = %s with %d reports, target at %d; want %s with %d, target at %d", i,
+				groups[i].TxSign, len(groups[i].Reports), groups[i].TargetIndex, w.txSign, w.reports, w.targetIndex)
+		}
+	}
+}
+
+// TestProcessRawTxReports_MissingTarget tests that a group without the target receiver is an error.
+func TestProcessRawTxReports_MissingTarget(t *testing.T) {
+	raw := []ReceptionReport{{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH"}}
+
+	if _, err := ProcessRawTxReports(raw, ExactCallsign("W5ABC")); err == nil {
+		t.Errorf("ProcessRawTxReports() expected error for missing target, got nil")
+	}
+}
+
+// TestRunRxAnalysis tests the receiver-perspective analysis end to end.
+func TestRunRxAnalysis(t *testing.T) {
+	source := &fakeRxSource{reports: append([]ReceptionReport(nil), testRxReports...)}
+	params := testParams
+	params.TargetCallsign = "W5ABC"
+
+	result, err := RunRxAnalysis(source, params)
+
+	if err != nil {
+		t.Fatalf("RunRxAnalysis() unexpected error: %v", err)
+	}
+	// The distant receiver is not comparable and the last transmission was
+	// only heard by the target.
+	if len(result.Groups) != 2 || len(result.FilteredOut) != 1 {
+		t.Fatalf("RunRxAnalysis() gave %d groups and %d filtered out, want 2 and 1", len(result.Groups), len(result.FilteredOut))
+	}
+	first := result.Groups[0]
+	if first.Rank != 2 || first.TargetSnr_dB != -12 || len(first.RelativeSnrs_dB) != 2 || first.PercentileRank != 50 {
+		t.Errorf("first group = %+v", first)
+	}
+	// Relative SNRs of the others: +4, -8 and -5; the target beat them by a
+	// median of 5dB.
+	if result.Aggregate.Samples != 3 || result.Aggregate.DbMedian != 5 {
+		t.Errorf("Aggregate = %+v, want 3 samples with median 5", result.Aggregate)
+	}
+	if result.PercentileRank.Groups != 2 || result.PercentileRank.Mean != 75 {
+		t.Errorf("PercentileRank = %+v, want mean 75 over 2 groups", result.PercentileRank)
+	}
+}
+
+// TestRunRxAnalysis_NoReports tests that an empty source gives ErrNoReports.
+func TestRunRxAnalysis_NoReports(t *testing.T) {
+	params := testParams
+	params.TargetCallsign = "W5ABC"
+
+	_, err := RunRxAnalysis(&fakeRxSource{}, params)
+
+	if !errors.Is(err, ErrNoReports) {
+		t.Errorf("RunRxAnalysis() error = %v, want wrapped ErrNoReports", err)
+	}
+}
+
+// TestWriteRxResultText tests the text output of a receiver-perspective analysis.
+func TestWriteRxResultText(t *testing.T) {
+	params := testParams
+	params.TargetCallsign = "W5ABC"
+	result, err := RunRxAnalysis(&fakeRxSource{reports: append([]ReceptionReport(nil), testRxReports...)}, params)
+	if err != nil {
+		t.Fatalf("RunRxAnalysis() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+
+	if err := WriteRxResultText(&buf, result, true); err != nil {
+		t.Fatalf("WriteRxResultText() unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"Transmission from N0OTH at 2024-12-14T15:32:00Z filtered out",
+		"Transmission from W5XYZ (distance 400km)",
+		"-->1: Receiver: W5ABC",
+		"2 out of 3 receivers; SNR: -12dB",
+		"+5.0dBmedian",
+		"Mean percentile rank among comparable receivers: 75.0% (2 groups)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("WriteRxResultText() output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+// TestRunRxAnalysis_DuplicatesRemovedByLocator tests that only the duplicates
+// in the transmissions heard from the chosen receiver locator are counted.
+func TestRunRxAnalysis_DuplicatesRemovedByLocator(t *testing.T) {
+	reports := []ReceptionReport{
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -12, Distance_km: 1000, RxLoc: "EM10"},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -8, Distance_km: 1100},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -9, Distance_km: 1100},
+		{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -13, Distance_km: 1000, RxLoc: "EM10"},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -10, Distance_km: 1000, RxLoc: "EM20"},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -8, Distance_km: 1100},
+		{TimeStr: "2024-12-14 15:32:00", RxSign: "W5DEF", TxSign: "N0OTH", Power_dBm: 23, Snr_dB: -7, Distance_km: 1100},
+	}
+	params := testParams
+	params.TargetCallsign = "W5ABC"
+
+	result, err := RunRxAnalysis(&fakeRxSource{reports: slices.Clone(reports)}, params)
+	if err != nil {
+		t.Fatalf("RunRxAnalysis() unexpected error: %v", err)
+	}
+	if result.DuplicatesRemoved != 3 {
+		t.Errorf("RunRxAnalysis() DuplicatesRemoved = %d, want 3", result.DuplicatesRemoved)
+	}
+
+	params.TargetLocator = "EM20"
+	result, err = RunRxAnalysis(&fakeRxSource{reports: slices.Clone(reports)}, params)
+	if err != nil {
+		t.Fatalf("RunRxAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || result.DuplicatesRemoved != 1 {
+		t.Errorf("RunRxAnalysis() gave %d groups and %d duplicates removed, want 1 and 1", len(result.Groups), result.DuplicatesRemoved)
+	}
+}
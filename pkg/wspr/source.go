@@ -0,0 +1,29 @@
+// This file defines the interface through which the analysis obtains its raw
+// reception reports, so that the processing pipeline is independent of where
+// the data actually comes from.
+
+package wspr
+
+import "time"
+
+// ReportSource is implemented by anything which can supply raw reception
+// reports for analysis (e.g. the wspr.live database, local files or a cache).
+// Implementations must be safe for concurrent use, as RunMultiBandAnalysis
+// fetches several bands at once.
+type ReportSource interface {
+	// FetchReports returns all the reception reports on band in the time range
+	// [startTime, startTime+duration) made by receivers which also heard a
+	// transmitter matched by target on the same band in the same time slot.
+	// The reports must be ordered by time followed by receiver callsign.
+	FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error)
+}
+
+// RxReportSource is implemented by sources which can also supply raw reports
+// for receiver-perspective analysis (see RunRxAnalysis).
+type RxReportSource interface {
+	// FetchRxReports returns all the reception reports on band in the time
+	// range [startTime, startTime+duration) of transmitters which a receiver
+	// matched by target also heard on the same band in the same time slot.
+	// The reports must be ordered by time followed by transmitter callsign.
+	FetchRxReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error)
+}
@@ -0,0 +1,82 @@
+package wspr
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeSource is a ReportSource which returns canned reports, for testing.
+type fakeSource struct {
+	reports []ReceptionReport
+	err     error
+	calls   int
+}
+
+func (f *fakeSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	f.calls++
+	return f.reports, f.err
+}
+
+// Analysis parameters shared by the tests in this file.
+var testParams = AnalysisParams{
+	TargetCallsign: "W5XYZ",
+	Band:           14,
+	StartTime:      time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+	Duration:       24 * time.Hour,
+	NormTxPwr_dBm:  43,
+}
+
+// TestRunAnalysis_FakeSource tests that RunAnalysis uses the supplied ReportSource.
+func TestRunAnalysis_FakeSource(t *testing.T) {
+	source := &fakeSource{
+		reports: []ReceptionReport{
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 200},
+			{TimeStr: "2024-12-14 15:30:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 20, Snr_dB: -15, Distance_km: 210},
+		},
+	}
+
+	result, err := RunAnalysis(source, testParams)
+
+	if err != nil {
+		t.Fatalf("RunAnalysis() unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || result.Aggregate.Samples != 1 {
+		t.Errorf("RunAnalysis() result = %+v", result)
+	}
+	if source.calls != 1 {
+		t.Errorf("RunAnalysis() called FetchReports %d times, want 1", source.calls)
+	}
+}
+
+// TestRunAnalysis_SourceError tests that RunAnalysis propagates errors from the ReportSource.
+func TestRunAnalysis_SourceError(t *testing.T) {
+	sourceErr := errors.New("source unavailable")
+	source := &fakeSource{err: sourceErr}
+
+	_, err := RunAnalysis(source, testParams)
+
+	if !errors.Is(err, sourceErr) {
+		t.Errorf("RunAnalysis() error = %v, want wrapped %v", err, sourceErr)
+	}
+}
+
+// TestRunAnalysis_NoReports tests that RunAnalysis returns an error when the source is empty.
+func TestRunAnalysis_NoReports(t *testing.T) {
+	source := &fakeSource{}
+
+	_, err := RunAnalysis(source, testParams)
+
+	if err == nil {
+		t.Errorf("RunAnalysis() expected error for empty source, got nil")
+	}
+}
+
+// TestRunAnalysis_NoReportsIsErrNoReports tests that an empty source gives ErrNoReports.
+func TestRunAnalysis_NoReportsIsErrNoReports(t *testing.T) {
+	_, err := RunAnalysis(&fakeSource{}, testParams)
+
+	if !errors.Is(err, ErrNoReports) {
+		t.Errorf("RunAnalysis() error = %v, want wrapped ErrNoReports", err)
+	}
+}
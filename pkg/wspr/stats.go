@@ -0,0 +1,137 @@
+// This file contains general statistical helper functions.
+
+package wspr
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Confidence level used for all the confidence intervals calculated by the
+// package.
+const ConfidenceLevel = 0.95
+
+// A confidence interval for some statistic. Level is the confidence level
+// actually achieved, which may exceed the one requested. Low and High are only
+// meaningful if Valid is true.
+type ConfidenceInterval struct {
+	Low   float64
+	High  float64
+	Level float64
+	Valid bool
+}
+
+// Calculate the cumulative probability P(X <= k) where X is binomially
+// distributed with n trials and success probability 0.5.
+func binomialHalfCDF(n, k int) float64 {
+	if k < 0 {
+		return 0
+	}
+	lgammaN, _ := math.Lgamma(float64(n + 1))
+	var cdf float64
+	for i := 0; i <= k && i <= n; i++ {
+		lgammaI, _ := math.Lgamma(float64(i + 1))
+		lgammaNI, _ := math.Lgamma(float64(n - i + 1))
+		cdf += math.Exp(lgammaN - lgammaI - lgammaNI - float64(n)*math.Ln2)
+	}
+	return min(cdf, 1)
+}
+
+// Calculate a distribution-free confidence interval for the median of the
+// population from which values were drawn, using order statistics (i.e. the
+// interval inverts the sign test). The interval is the narrowest one whose
+// coverage is at least level. It is invalid if there are too few values to
+// achieve that coverage.
+// NOTE: values will be sorted in place.
+func medianConfidenceInterval[T ~int8 | ~int | ~float64](values []T, level float64) ConfidenceInterval {
+	n := len(values)
+	alpha := (1 - level) / 2
+	// The interval [values[j], values[n-1-j]] (once sorted) fails to contain
+	// the median with probability 2*P(X <= j), where X ~ Binomial(n, 0.5).
+	// Find the largest j for which that is acceptable.
+	j := -1
+	for j+1 <= (n-1)/2 && binomialHalfCDF(n, j+1) <= alpha {
+		j++
+	}
+	if j < 0 {
+		return ConfidenceInterval{}
+	}
+	slices.Sort(values)
+	return ConfidenceInterval{
+		Low:   float64(values[j]),
+		High:  float64(values[n-1-j]),
+		Level: 1 - 2*binomialHalfCDF(n, j),
+		Valid: true,
+	}
+}
+
+// Format a confidence interval for printing, e.g. "95% CI [-1.0, +2.5]dB".
+func (ci ConfidenceInterval) String() string {
+	if !ci.Valid {
+		return "CI unavailable (too few samples)"
+	}
+	return fmt.Sprintf("%.0f%% CI [%+.1f, %+.1f]dB", ci.Level*100, ci.Low, ci.High)
+}
+
+// Calculate the q-quantile (0 <= q <= 1) of a sorted slice by linear
+// interpolation between the closest ranks. The slice must not be empty.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// Perform a two-sided Mann-Whitney U test of whether the values in a and b
+// come from the same distribution. Returns the U statistic for a and the
+// p-value, using the normal approximation with a correction for ties (which
+// are common with whole-dB SNRs). The p-value is 1 if either slice is empty.
+func mannWhitneyU(a, b []float64) (float64, float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	type rankedValue struct {
+		value float64
+		fromA bool
+	}
+	combined := make([]rankedValue, 0, len(a)+len(b))
+	for _, value := range a {
+		combined = append(combined, rankedValue{value, true})
+	}
+	for _, value := range b {
+		combined = append(combined, rankedValue{value, false})
+	}
+	slices.SortFunc(combined, func(x, y rankedValue) int {
+		return cmp.Compare(x.value, y.value)
+	})
+	// Sum the ranks of the values from a, giving tied values the mean of the
+	// ranks they span, and accumulate the tie correction term.
+	var rankSumA, tieCorrection float64
+	for i := 0; i < len(combined); {
+		j := i
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+		meanRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if combined[k].fromA {
+				rankSumA += meanRank
+			}
+		}
+		ties := float64(j - i)
+		tieCorrection += ties*ties*ties - ties
+		i = j
+	}
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	variance := n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	// Continuity correction of 0.5 towards the mean.
+	z := (math.Abs(u-n1*n2/2) - 0.5) / math.Sqrt(variance)
+	return u, min(math.Erfc(max(z, 0)/math.Sqrt2), 1)
+}
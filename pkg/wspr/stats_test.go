@@ -0,0 +1,128 @@
+package wspr
+
+import (
+	"math"
+	"testing"
+)
+
+// TestBinomialHalfCDF tests the binomialHalfCDF function against exact values.
+func TestBinomialHalfCDF(t *testing.T) {
+	tests := []struct {
+		n, k int
+		want float64
+	}{
+		{n: 4, k: -1, want: 0},
+		{n: 4, k: 0, want: 1.0 / 16},
+		{n: 4, k: 1, want: 5.0 / 16},
+		{n: 4, k: 4, want: 1},
+		{n: 10, k: 1, want: 11.0 / 1024},
+	}
+
+	for _, tt := range tests {
+		if result := binomialHalfCDF(tt.n, tt.k); math.Abs(result-tt.want) > 1e-12 {
+			t.Errorf("binomialHalfCDF(%d, %d) = %v, want %v", tt.n, tt.k, result, tt.want)
+		}
+	}
+}
+
+// TestMedianConfidenceInterval tests the order statistic confidence interval.
+func TestMedianConfidenceInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []int
+		wantValid bool
+		wantLow   float64
+		wantHigh  float64
+	}{
+		{
+			name:      "too few values",
+			values:    []int{1, 2, 3, 4, 5},
+			wantValid: false,
+		},
+		{
+			name:      "six values uses extremes",
+			values:    []int{6, 1, 5, 2, 4, 3},
+			wantValid: true,
+			wantLow:   1,
+			wantHigh:  6,
+		},
+		{
+			// For n = 10, P(X <= 1) = 0.0107 is acceptable but P(X <= 2) = 0.0547 is not.
+			name:      "ten values drops one from each end",
+			values:    []int{10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+			wantValid: true,
+			wantLow:   2,
+			wantHigh:  9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := medianConfidenceInterval(tt.values, ConfidenceLevel)
+			if result.Valid != tt.wantValid {
+				t.Fatalf("medianConfidenceInterval() Valid = %v, want %v", result.Valid, tt.wantValid)
+			}
+			if tt.wantValid {
+				if result.Low != tt.wantLow || result.High != tt.wantHigh {
+					t.Errorf("medianConfidenceInterval() = [%v, %v], want [%v, %v]", result.Low, result.High, tt.wantLow, tt.wantHigh)
+				}
+				if result.Level < ConfidenceLevel {
+					t.Errorf("medianConfidenceInterval() Level = %v, want at least %v", result.Level, ConfidenceLevel)
+				}
+			}
+		})
+	}
+}
+
+// TestMannWhitneyU tests the U statistic and normal approximation p-value.
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  []float64
+		wantU float64
+		wantP float64
+	}{
+		{
+			// var = 3*3/12*7 = 5.25, z = (4.5-0.5)/sqrt(5.25)
+			name:  "separated",
+			a:     []float64{1, 2, 3},
+			b:     []float64{4, 5, 6},
+			wantU: 0,
+			wantP: 0.0808,
+		},
+		{
+			name:  "identical",
+			a:     []float64{1, 2, 3},
+			b:     []float64{1, 2, 3},
+			wantU: 4.5,
+			wantP: 1,
+		},
+		{
+			// Ties give var = 2*2/12*(5 - 12/12) = 4/3, z = 1.5/sqrt(4/3)
+			name:  "tied",
+			a:     []float64{-3, -3},
+			b:     []float64{5, 5},
+			wantU: 0,
+			wantP: 0.1939,
+		},
+		{
+			name:  "empty",
+			a:     nil,
+			b:     []float64{1},
+			wantU: 0,
+			wantP: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p := mannWhitneyU(tt.a, tt.b)
+			if u != tt.wantU {
+				t.Errorf("mannWhitneyU() U = %v, want %v", u, tt.wantU)
+			}
+			if math.Abs(p-tt.wantP) > 1e-4 {
+				t.Errorf("mannWhitneyU() p = %.4f, want %.4f", p, tt.wantP)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// This file contains the trend analysis, which tracks the aggregate metric
+// over a long period by analysing it in a series of shorter buckets.
+
+package wspr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// The result of the analysis of one bucket of a trend.
+type TrendPoint struct {
+	StartTime time.Time
+	Duration  time.Duration
+	Groups    int
+	Aggregate AggregateMetric
+	// Number of duplicate spots merged (see AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+}
+
+// The result of a trend analysis. AnalysisParams covers the whole period.
+type TrendResult struct {
+	AnalysisParams
+	Points []TrendPoint
+	// Number of duplicate spots merged in all the buckets.
+	DuplicatesRemoved int
+}
+
+// RunTrend splits the period described by params into consecutive buckets of
+// length bucket (the last may be shorter) and runs the full analysis on each
+// in turn. The reports for the whole period are fetched from source once.
+// Buckets with no reports for the target give points with no samples rather
+// than an error.
+func RunTrend(source ReportSource, params AnalysisParams, bucket time.Duration) (*TrendResult, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("trend bucket length must be positive")
+	}
+	rawRxReports, err := source.FetchReports(params.Target(), params.Band, params.StartTime, params.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reception reports (%w)", err)
+	}
+	fetched := fetchedSource{reports: rawRxReports}
+	result := &TrendResult{AnalysisParams: params}
+	endTime := params.StartTime.Add(params.Duration)
+	for bucketStart := params.StartTime; bucketStart.Before(endTime); bucketStart = bucketStart.Add(bucket) {
+		bucketParams := params
+		bucketParams.StartTime = bucketStart
+		bucketParams.Duration = min(bucket, endTime.Sub(bucketStart))
+		point := TrendPoint{StartTime: bucketStart, Duration: bucketParams.Duration}
+		bucketResult, err := RunAnalysis(fetched, bucketParams)
+		if err != nil && !errors.Is(err, ErrNoReports) {
+			return nil, fmt.Errorf("error analysing bucket starting %s (%w)", bucketStart.UTC().Format(time.RFC3339), err)
+		}
+		if err == nil {
+			point.Groups = len(bucketResult.Groups)
+			point.Aggregate = bucketResult.Aggregate
+			point.DuplicatesRemoved = bucketResult.DuplicatesRemoved
+			result.DuplicatesRemoved += point.DuplicatesRemoved
+		}
+		result.Points = append(result.Points, point)
+	}
+	return result, nil
+}
+
+// WriteTrend writes a trend result to w in the given format. Only the Charts
+// field of textOptions is used, to draw a bar chart of the metric.
+func WriteTrend(w io.Writer, result *TrendResult, format OutputFormat, textOptions TextOptions) error {
+	switch format {
+	case FormatJSON:
+		return writeTrendJSON(w, result)
+	case FormatCSV:
+		return writeTrendCSV(w, result, ',')
+	case FormatTSV:
+		return writeTrendCSV(w, result, '\t')
+	default:
+		return writeTrendText(w, result, textOptions)
+	}
+}
+
+// Write a trend result as a table.
+func writeTrendText(w io.Writer, result *TrendResult, options TextOptions) error {
+	writeDuplicatesText(w, result.DuplicatesRemoved, result.Duplicates)
+	fmt.Fprintf(w, "Offset from median of relative normalised SNR of all other transmitters for %s:\n", result.TargetCallsign)
+	fmt.Fprintf(w, "    %-20s %10s %8s %8s\n", "Start", "dBmedian", "Samples", "Groups")
+	chart := Breakdown{Name: "time", Metric: "dBmedian"}
+	for _, point := range result.Points {
+		dbMedian := "-"
+		if point.Aggregate.Valid() {
+			dbMedian = fmt.Sprintf("%+.1f", point.Aggregate.DbMedian)
+		}
+		fmt.Fprintf(w, "    %-20s %10s %8d %8d\n", point.StartTime.UTC().Format(time.RFC3339), dbMedian, point.Aggregate.Samples, point.Groups)
+		bin := BreakdownBin{Label: point.StartTime.UTC().Format("2006-01-02 15:04")}
+		if point.Aggregate.Valid() {
+			bin.Median_dB, bin.Samples = point.Aggregate.DbMedian, point.Aggregate.Samples
+		}
+		chart.Bins = append(chart.Bins, bin)
+	}
+	if options.Charts {
+		writeBreakdownChart(w, chart)
+	}
+	return nil
+}
+
+// Top level of the JSON document written for a trend. The schema is versioned
+// along with the main document (see JSONSchemaVersion).
+type jsonTrendDocument struct {
+	SchemaVersion int              `json:"schema_version"`
+	Target        string           `json:"target"`
+	Band          int              `json:"band"`
+	StartTime     string           `json:"start_time"`
+	EndTime       string           `json:"end_time"`
+	NormPower_dBm int8             `json:"norm_power_dbm"`
+	Duplicates    jsonDuplicates   `json:"duplicates"`
+	Points        []jsonTrendPoint `json:"points"`
+}
+
+// JSON representation of a TrendPoint. DbMedian is null if there were too few
+// samples to calculate it.
+type jsonTrendPoint struct {
+	StartTime         string   `json:"start_time"`
+	EndTime           string   `json:"end_time"`
+	DbMedian          *float64 `json:"db_median"`
+	Samples           int      `json:"samples"`
+	Groups            int      `json:"groups"`
+	DuplicatesRemoved int      `json:"duplicates_removed"`
+}
+
+// Write a trend result as a JSON document.
+func writeTrendJSON(w io.Writer, result *TrendResult) error {
+	doc := jsonTrendDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Target:        result.TargetCallsign,
+		Band:          result.Band,
+		StartTime:     result.StartTime.UTC().Format(time.RFC3339),
+		EndTime:       result.StartTime.Add(result.Duration).UTC().Format(time.RFC3339),
+		NormPower_dBm: result.NormTxPwr_dBm,
+		Duplicates:    jsonDuplicates{Policy: result.Duplicates.String(), Removed: result.DuplicatesRemoved},
+		Points:        make([]jsonTrendPoint, 0, len(result.Points)),
+	}
+	for _, point := range result.Points {
+		jsonPoint := jsonTrendPoint{
+			StartTime:         point.StartTime.UTC().Format(time.RFC3339),
+			EndTime:           point.StartTime.Add(point.Duration).UTC().Format(time.RFC3339),
+			Samples:           point.Aggregate.Samples,
+			Groups:            point.Groups,
+			DuplicatesRemoved: point.DuplicatesRemoved,
+		}
+		if point.Aggregate.Valid() {
+			jsonPoint.DbMedian = &point.Aggregate.DbMedian
+		}
+		doc.Points = append(doc.Points, jsonPoint)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON output (%w)", err)
+	}
+	return nil
+}
+
+// Write a trend result as CSV with one row per point. db_median is empty if
+// there were too few samples to calculate it.
+func writeTrendCSV(w io.Writer, result *TrendResult, comma rune) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = comma
+	csvWriter.Write([]string{"start_time", "end_time", "db_median", "samples", "groups"})
+	for _, point := range result.Points {
+		dbMedian := ""
+		if point.Aggregate.Valid() {
+			dbMedian = strconv.FormatFloat(point.Aggregate.DbMedian, 'f', 1, 64)
+		}
+		csvWriter.Write([]string{
+			point.StartTime.UTC().Format(time.RFC3339),
+			point.StartTime.Add(point.Duration).UTC().Format(time.RFC3339),
+			dbMedian, strconv.Itoa(point.Aggregate.Samples), strconv.Itoa(point.Groups),
+		})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output (%w)", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package wspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// windowSource is a ReportSource which returns the canned reports falling in
+// the requested time range.
+type windowSource struct {
+	reports []ReceptionReport
+	calls   int
+}
+
+func (s *windowSource) FetchReports(target CallsignMatcher, band int, startTime time.Time, duration time.Duration) ([]ReceptionReport, error) {
+	s.calls++
+	var reports []ReceptionReport
+	for _, report := range s.reports {
+		if t := report.Time(); !t.Before(startTime) && t.Before(startTime.Add(duration)) {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// Reports for two days, the first with W5XYZ 5dB above N0OTH and the third
+// with W5XYZ 3dB below. There are none on the second day.
+var trendReports = []ReceptionReport{
+	{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -10, Distance_km: 200},
+	{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+	{TimeStr: "2024-12-14 10:00:00", RxSign: "W5ABC", TxSign: "G3ABC", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+	{TimeStr: "2024-12-16 10:00:00", RxSign: "W5ABC", TxSign: "W5XYZ", Power_dBm: 10, Snr_dB: -18, Distance_km: 200},
+	{TimeStr: "2024-12-16 10:00:00", RxSign: "W5ABC", TxSign: "N0OTH", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+	{TimeStr: "2024-12-16 10:00:00", RxSign: "W5ABC", TxSign: "G3ABC", Power_dBm: 10, Snr_dB: -15, Distance_km: 200},
+}
+
+// Parameters covering the trend reports, in daily buckets.
+var trendParams = AnalysisParams{
+	TargetCallsign: "W5XYZ",
+	Band:           14,
+	StartTime:      time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC),
+	Duration:       3 * 24 * time.Hour,
+	NormTxPwr_dBm:  43,
+}
+
+// TestRunTrend tests that the metric is calculated for each bucket.
+func TestRunTrend(t *testing.T) {
+	source := &windowSource{reports: trendReports}
+
+	result, err := RunTrend(source, trendParams, 24*time.Hour)
+
+	if err != nil {
+		t.Fatalf("RunTrend() unexpected error: %v", err)
+	}
+	if source.calls != 1 {
+		t.Errorf("RunTrend() called FetchReports %d times, want 1", source.calls)
+	}
+	if len(result.Points) != 3 {
+		t.Fatalf("RunTrend() returned %d points, want 3", len(result.Points))
+	}
+	if p := result.Points[0]; !p.Aggregate.Valid() || p.Aggregate.DbMedian != 5 || p.Groups != 1 {
+		t.Errorf("RunTrend() first point = %+v, want +5dB from 1 group", p)
+	}
+	if p := result.Points[1]; p.Aggregate.Samples != 0 || p.Groups != 0 || !p.StartTime.Equal(trendParams.StartTime.Add(24*time.Hour)) {
+		t.Errorf("RunTrend() second point = %+v, want empty", p)
+	}
+	if p := result.Points[2]; !p.Aggregate.Valid() || p.Aggregate.DbMedian != -3 {
+		t.Errorf("RunTrend() third point = %+v, want -3dB", p)
+	}
+}
+
+// TestRunTrend_PartialBucket tests that the last bucket is truncated to the period.
+func TestRunTrend_PartialBucket(t *testing.T) {
+	params := trendParams
+	params.Duration = 30 * time.Hour
+
+	result, err := RunTrend(&windowSource{reports: trendReports}, params, 24*time.Hour)
+
+	if err != nil {
+		t.Fatalf("RunTrend() unexpected error: %v", err)
+	}
+	if len(result.Points) != 2 || result.Points[1].Duration != 6*time.Hour {
+		t.Errorf("RunTrend() points = %+v, want 2 with the last 6h long", result.Points)
+	}
+}
+
+// TestRunTrend_SourceError tests that errors other than missing reports are returned.
+func TestRunTrend_SourceError(t *testing.T) {
+	sourceErr := errors.New("source unavailable")
+
+	_, err := RunTrend(&fakeSource{err: sourceErr}, trendParams, 24*time.Hour)
+
+	if !errors.Is(err, sourceErr) {
+		t.Errorf("RunTrend() error = %v, want wrapped %v", err, sourceErr)
+	}
+}
+
+// TestWriteTrend tests the output formats for a trend.
+func TestWriteTrend(t *testing.T) {
+	result, err := RunTrend(&windowSource{reports: trendReports}, trendParams, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("RunTrend() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTrend(&buf, result, FormatText, TextOptions{Charts: true}); err != nil {
+		t.Fatalf("WriteTrend() unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2024-12-14T00:00:00Z") || !strings.Contains(buf.String(), "+5.0") || !strings.Contains(buf.String(), "####") {
+		t.Errorf("WriteTrend() text output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteTrend(&buf, result, FormatCSV, TextOptions{}); err != nil {
+		t.Fatalf("WriteTrend() unexpected error: %v", err)
+	}
+	want := "start_time,end_time,db_median,samples,groups\n" +
+		"2024-12-14T00:00:00Z,2024-12-15T00:00:00Z,5.0,2,1\n" +
+		"2024-12-15T00:00:00Z,2024-12-16T00:00:00Z,,0,0\n" +
+		"2024-12-16T00:00:00Z,2024-12-17T00:00:00Z,-3.0,2,1\n"
+	if buf.String() != want {
+		t.Errorf("WriteTrend() CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteTrend(&buf, result, FormatJSON, TextOptions{}); err != nil {
+		t.Fatalf("WriteTrend() unexpected error: %v", err)
+	}
+	var doc struct {
+		Points []struct {
+			DbMedian *float64 `json:"db_median"`
+		} `json:"points"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteTrend() produced invalid JSON: %v", err)
+	}
+	if len(doc.Points) != 3 || doc.Points[1].DbMedian != nil || *doc.Points[2].DbMedian != -3 {
+		t.Errorf("WriteTrend() JSON points = %+v", doc.Points)
+	}
+}
+
+// TestRunTrend_DuplicatesRemoved tests that the merged spots are counted per
+// bucket and shown in the text and JSON output.
+func TestRunTrend_DuplicatesRemoved(t *testing.T) {
+	reports := append(slices.Clone(trendReports), trendReports[1], trendReports[3], trendReports[4])
+
+	result, err := RunTrend(&windowSource{reports: reports}, trendParams, 24*time.Hour)
+
+	if err != nil {
+		t.Fatalf("RunTrend() unexpected error: %v", err)
+	}
+	if result.DuplicatesRemoved != 3 || result.Points[0].DuplicatesRemoved != 1 || result.Points[2].DuplicatesRemoved != 2 {
+		t.Errorf("RunTrend() removed %d duplicates, points %+v", result.DuplicatesRemoved, result.Points)
+	}
+	var buf bytes.Buffer
+	WriteTrend(&buf, result, FormatText, TextOptions{})
+	if !strings.Contains(buf.String(), "Removed 3 duplicate spots (policy max-snr)") {
+		t.Errorf("WriteTrend() text output:\n%s", buf.String())
+	}
+	buf.Reset()
+	WriteTrend(&buf, result, FormatJSON, TextOptions{})
+	var doc struct {
+		Duplicates jsonDuplicates `json:"duplicates"`
+		Points     []struct {
+			DuplicatesRemoved int `json:"duplicates_removed"`
+		} `json:"points"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteTrend() produced invalid JSON: %v", err)
+	}
+	if doc.Duplicates.Removed != 3 || len(doc.Points) != 3 || doc.Points[2].DuplicatesRemoved != 2 {
+		t.Errorf("WriteTrend() JSON = %s", buf.String())
+	}
+}
@@ -0,0 +1,294 @@
+// General definitions and types used throughout the package.
+
+package wspr
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct to hold a single WSPR reception report. This maps to the JSON
+// returned by the wspr.live database query.
+type ReceptionReport struct {
+	TimeStr     string `json:"time"`
+	RxSign      string `json:"rx_sign"`
+	TxSign      string `json:"tx_sign"`
+	Power_dBm   int8   `json:"power"`
+	Snr_dB      int8   `json:"snr"`
+	Distance_km uint16 `json:"distance"`
+	TxAzimuth   uint16 `json:"azimuth"`
+	RxAzimuth   uint16 `json:"rx_azimuth"`
+	// Maidenhead locators and positions of the transmitter and receiver, and
+	// the frequency of the spot. The positions are zero if unknown.
+	TxLoc        string  `json:"tx_loc"`
+	RxLoc        string  `json:"rx_loc"`
+	TxLat_deg    float64 `json:"tx_lat"`
+	TxLon_deg    float64 `json:"tx_lon"`
+	RxLat_deg    float64 `json:"rx_lat"`
+	RxLon_deg    float64 `json:"rx_lon"`
+	Frequency_Hz uint32  `json:"frequency"`
+}
+
+// Method to parse the TimeStr field of the above struct into a time.Time
+// object.
+func (r *ReceptionReport) Time() time.Time {
+	t, err := time.Parse(time.DateTime, r.TimeStr)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Method to calculate the normalised SNR for this reception report given a
+// reference transmitter power in dBm. The normalised SNR is the SNR that this
+// transmission would have if the transmitter were transmitting at txRefPower_dBm.
+func (r *ReceptionReport) SnrNorm_dB(txRefPower_dBm int8) int8 {
+	return r.Snr_dB + txRefPower_dBm - r.Power_dBm
+}
+
+// Method to get the position of the transmitter in degrees. ok is false if
+// the report has no transmitter locator. The reported latitude and longitude
+// are used if present, otherwise the centre of the locator square.
+func (r *ReceptionReport) TxLatLon() (lat_deg, lon_deg float64, ok bool) {
+	return stationLatLon(r.TxLoc, r.TxLat_deg, r.TxLon_deg)
+}
+
+// Method to get the position of the receiver in degrees (see TxLatLon).
+func (r *ReceptionReport) RxLatLon() (lat_deg, lon_deg float64, ok bool) {
+	return stationLatLon(r.RxLoc, r.RxLat_deg, r.RxLon_deg)
+}
+
+// Method to calculate the great-circle distance between the transmitter and
+// receiver from their positions. ok is false if either position is unknown.
+func (r *ReceptionReport) PathDistance_km() (distance_km float64, ok bool) {
+	txLat, txLon, txOk := r.TxLatLon()
+	rxLat, rxLon, rxOk := r.RxLatLon()
+	if !txOk || !rxOk {
+		return 0, false
+	}
+	return GreatCircleDistance_km(txLat, txLon, rxLat, rxLon), true
+}
+
+// Work out the position of a station from its locator and reported position.
+func stationLatLon(locator string, lat_deg, lon_deg float64) (float64, float64, bool) {
+	if locator == "" {
+		return 0, 0, false
+	}
+	if lat_deg != 0 || lon_deg != 0 {
+		return lat_deg, lon_deg, true
+	}
+	lat_deg, lon_deg, err := LocatorToLatLon(locator)
+	return lat_deg, lon_deg, err == nil
+}
+
+// Struct to hold a group of reception reports received by a single station at
+// a given time, including the index of the target transmitter's report within
+// the group.
+type ReceptionReportGroup struct {
+	RxSign      string
+	Time        time.Time
+	Reports     []ReceptionReport
+	TargetIndex int
+}
+
+// Struct to hold a group of reception reports of a single transmitter at a
+// given time by different receivers, including the index of the target
+// receiver's report within the group. This mirrors ReceptionReportGroup for
+// receiver-perspective analysis (see RunRxAnalysis).
+type TransmissionReportGroup struct {
+	TxSign      string
+	Time        time.Time
+	Reports     []ReceptionReport
+	TargetIndex int
+}
+
+// Parameters describing what to analyse.
+type AnalysisParams struct {
+	TargetCallsign string
+	// Other callsigns of the target, and whether to match its compound
+	// callsigns (see CallsignMatcher).
+	TargetAliases     []string
+	CompoundCallsigns bool
+	Band              int
+	StartTime         time.Time
+	Duration          time.Duration
+	NormTxPwr_dBm     int8
+	// Width of the sectors for a breakdown of the results by azimuth from the
+	// target (see AzimuthBreakdown). Zero disables the breakdown.
+	AzimuthSectorWidth_deg float64
+	// Lower bounds of the bands for a breakdown of the results by distance
+	// (see DistanceBreakdown). Empty disables the breakdown.
+	DistanceBands_km []float64
+	// Produce a breakdown of the results by hour of day (see HourlyBreakdown).
+	// The hours are in UTC, unless SolarTime is set in which case they are in
+	// local mean solar time at TargetLongitude_deg.
+	HourlyProfile       bool
+	SolarTime           bool
+	TargetLongitude_deg float64
+	// Number of bootstrap resamples for confidence intervals, and the seed of
+	// the random number generator used to draw them. Zero iterations disables
+	// bootstrapping.
+	BootstrapIterations int
+	BootstrapSeed       uint64
+	// Rules for choosing the transmitters to compare with the target. Nil
+	// means DefaultFilterChain.
+	Filter *FilterChain
+	// Receivers and transmitters to exclude before filtering. Nil excludes
+	// nothing.
+	Exclusions *ExclusionLists
+	// What to do with receivers detected as outliers after filtering (see
+	// DetectOutlierReceivers), and the robust z-score beyond which they are
+	// outliers. A zero threshold means DefaultOutlierThreshold.
+	OutlierMode      OutlierMode
+	OutlierThreshold float64
+	// How the samples of the groups are combined into the aggregate metric.
+	Aggregation AggregationStrategy
+	// Only use the groups in which the target reported from within this
+	// locator square (see MatchLocator). Empty uses every group.
+	TargetLocator string
+	// How duplicate spots of a transmission by one receiver are merged (see
+	// GroupRxReports).
+	Duplicates DuplicatePolicy
+}
+
+// Return the matcher for the target's callsigns described by p.
+func (p AnalysisParams) Target() CallsignMatcher {
+	return CallsignMatcher{Callsign: p.TargetCallsign, Aliases: p.TargetAliases, Compound: p.CompoundCallsigns}
+}
+
+// Statistics for a single ReceptionReportGroup, describing how the target
+// transmitter compares with the others in the group.
+type GroupResult struct {
+	ReceptionReportGroup
+	// 1-based position of the target when ordered by descending normalised SNR.
+	Rank                 int
+	TargetSnrNorm_dB     int8
+	SnrNormOverMedian_dB int8
+	// Percentage of the other transmitters which the target beat, counting
+	// ties as half (0-100).
+	PercentileRank float64
+	// Normalised SNRs of the non-target transmitters relative to the target.
+	RelativeSnrNorms_dB []int8
+}
+
+// The aggregate metric across all groups: the negated median of all the
+// relative normalised SNRs, weighted according to the AggregationStrategy.
+// DbMedian is only meaningful if Valid() is true. Confidence is a bootstrap
+// confidence interval for DbMedian, calculated only if
+// AnalysisParams.BootstrapIterations is non-zero.
+type AggregateMetric struct {
+	DbMedian   float64
+	Samples    int
+	Confidence ConfidenceInterval
+}
+
+// Report whether there were enough samples to calculate the aggregate metric.
+func (a AggregateMetric) Valid() bool {
+	return a.Samples > 1
+}
+
+// The mean across groups of the target's percentile rank in each group. Mean
+// is only meaningful if Groups is non-zero. 50% means the target is typical
+// of the transmitters it was compared with.
+type PercentileRankMetric struct {
+	Mean   float64
+	Groups int
+}
+
+// The complete result of an analysis, independent of how it is presented.
+type AnalysisResult struct {
+	AnalysisParams
+	Groups []GroupResult
+	// Groups which were dropped for lack of comparable transmitters.
+	FilteredOut []ReceptionReportGroup
+	// Number of duplicate spots merged (see AnalysisParams.Duplicates).
+	DuplicatesRemoved int
+	// Every locator the target reported from, including any not selected by
+	// AnalysisParams.TargetLocator.
+	TargetLocators []LocatorSpan
+	// Receivers and transmitters removed by AnalysisParams.Exclusions.
+	Excluded Exclusions
+	// Receivers detected as outliers. Their groups have been dropped if
+	// AnalysisParams.OutlierMode is OutliersDrop.
+	Outliers  []ReceiverSpread
+	Aggregate AggregateMetric
+	// The aggregate metric calculated with every strategy, for comparison.
+	StrategyAggregates []StrategyAggregate
+	PercentileRank     PercentileRankMetric
+	// Any breakdowns of the results requested in the AnalysisParams.
+	Breakdowns []Breakdown
+}
+
+// Map between common band names and their corresponding integer codes used by
+// wspr.live.
+var bandNameToCode = map[string]int{
+	"lf":   -1,
+	"mf":   0,
+	"160m": 1,
+	"80m":  3,
+	"60m":  5,
+	"40m":  7,
+	"30m":  10,
+	"20m":  14,
+	"17m":  18,
+	"15m":  21,
+	"12m":  24,
+	"10m":  28,
+	"6m":   50,
+	"4m":   70,
+	"2m":   144,
+	"70cm": 432,
+	"23cm": 1296,
+}
+
+// Return all the band names (useful for the CLI help text).
+func BandNames() []string {
+	names := make([]string, 0, len(bandNameToCode))
+	for k := range bandNameToCode {
+		names = append(names, k)
+	}
+	return names
+}
+
+// Convert a band code to its name, or the code as a string if it has no name.
+func BandCodeToName(code int) string {
+	for name, c := range bandNameToCode {
+		if c == code {
+			return name
+		}
+	}
+	return strconv.Itoa(code)
+}
+
+// Convert a comma-separated list of band names, or "all", to a list of band
+// codes in ascending order of frequency without duplicates.
+func ParseBandList(list string) ([]int, error) {
+	var codes []int
+	if strings.EqualFold(strings.TrimSpace(list), "all") {
+		for _, code := range bandNameToCode {
+			codes = append(codes, code)
+		}
+	} else {
+		for _, bandName := range strings.Split(list, ",") {
+			code, err := BandNameToCode(strings.TrimSpace(bandName))
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes), nil
+}
+
+// Convert a band name to its corresponding integer code. Returns an error
+// if the band name is not recognised.
+func BandNameToCode(bandName string) (int, error) {
+	if code, ok := bandNameToCode[strings.ToLower(bandName)]; ok {
+		return code, nil
+	}
+	return 0, fmt.Errorf("unrecognised band name: %s", bandName)
+}
@@ -1,6 +1,8 @@
-package wspranalysis
+package wspr
 
 import (
+	"math"
+	"slices"
 	"testing"
 	"time"
 )
@@ -229,3 +231,63 @@ func TestBandNamesCaseInsensitivity(t *testing.T) {
 		}
 	}
 }
+
+// TestParseBandList tests lists of bands and "all".
+func TestParseBandList(t *testing.T) {
+	tests := []struct {
+		list      string
+		want      []int
+		wantError bool
+	}{
+		{list: "20m", want: []int{14}},
+		{list: "20m, 40M,20m", want: []int{7, 14}},
+		{list: "ALL", want: []int{-1, 0, 1, 3, 5, 7, 10, 14, 18, 21, 24, 28, 50, 70, 144, 432, 1296}},
+		{list: "20m,11m", wantError: true},
+		{list: "", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseBandList(tt.list)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseBandList(%q) error = %v, wantError %v", tt.list, err, tt.wantError)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseBandList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBandCodeToName tests named and unnamed band codes.
+func TestBandCodeToName(t *testing.T) {
+	if name := BandCodeToName(14); name != "20m" {
+		t.Errorf("BandCodeToName(14) = %q, want \"20m\"", name)
+	}
+	if name := BandCodeToName(99); name != "99" {
+		t.Errorf("BandCodeToName(99) = %q, want \"99\"", name)
+	}
+}
+
+// TestReceptionReportPositions tests the station positions and path distance.
+func TestReceptionReportPositions(t *testing.T) {
+	report := ReceptionReport{TxLoc: "IO91", RxLoc: "FN31", RxLat_deg: 41.5, RxLon_deg: -73}
+
+	txLat, txLon, ok := report.TxLatLon()
+	if !ok || txLat != 51.5 || txLon != -1 {
+		t.Errorf("TxLatLon() = %v, %v, %v, want the centre of IO91", txLat, txLon, ok)
+	}
+	rxLat, rxLon, ok := report.RxLatLon()
+	if !ok || rxLat != 41.5 || rxLon != -73 {
+		t.Errorf("RxLatLon() = %v, %v, %v, want the reported position", rxLat, rxLon, ok)
+	}
+	distance, ok := report.PathDistance_km()
+	if !ok || math.Abs(distance-GreatCircleDistance_km(51.5, -1, 41.5, -73)) > 1e-9 {
+		t.Errorf("PathDistance_km() = %v, %v", distance, ok)
+	}
+
+	report.TxLoc = ""
+	if _, ok := report.PathDistance_km(); ok {
+		t.Errorf("PathDistance_km() ok without a transmitter locator")
+	}
+}